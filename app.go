@@ -9,6 +9,7 @@
 //   - Method-level permissions with PathInterceptors
 //   - Telemetry (logging, tracing, metrics)
 //   - Integration with Connect's native compression
+//   - Local HTTP server mode (HTTPHandler, ListenAndServe)
 //
 // # Core API
 //
@@ -94,19 +95,22 @@
 package dindenault
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/aws/aws-lambda-go/events"
 
 	"github.com/navigacontentlab/dindenault/cors"
 	"github.com/navigacontentlab/dindenault/internal/lambda"
+	"github.com/navigacontentlab/dindenault/navigaid"
 )
 
 // App handles Connect services in Lambda.
@@ -117,7 +121,20 @@ type App struct {
 	telemetryProvider  TelemetryProvider
 	telemetryOptions   TelemetryOptions
 	corsOptions        *cors.Options
+	binaryMediaTypes   []string
 	prepareOnce        sync.Once
+
+	maxResponseSize          int
+	oversizedResponseHandler OversizedResponseHandler
+	panicHandler             PanicHandler
+	deadlineMargin           time.Duration
+	logOptions               LogOptions
+	validateAuthRules        bool
+	auditSink                navigaid.AuditSink
+
+	router                  *http.ServeMux
+	notFoundHandler         http.Handler
+	methodNotAllowedHandler http.Handler
 }
 
 // GlobalInterceptors returns the list of global interceptors for testing.
@@ -147,10 +164,21 @@ type Registration struct {
 	SkipGlobalInterceptors bool
 }
 
-// New creates a new App with the given options.
+// New creates a new App with the given options. A nil logger uses
+// slog.Default.
 func New(logger *slog.Logger, options ...Option) *App {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	// Wrap the logger before applying options, so that the components
+	// they create log with request context and redaction too.
+	handler, _ := NewContextHandler(logger.Handler(), LogOptions{}).(*contextHandler)
+
 	app := &App{
-		logger: logger,
+		logger:           slog.New(handler),
+		binaryMediaTypes: DefaultBinaryMediaTypes(),
+		maxResponseSize:  DefaultMaxResponseSize,
 	}
 
 	// Apply options
@@ -158,34 +186,27 @@ func New(logger *slog.Logger, options ...Option) *App {
 		opt(app)
 	}
 
-	return app
-}
+	handler.configure(app.logOptions)
 
-// pathMatches checks if a request path matches a registered service path.
-func (a *App) pathMatches(requestPath, servicePath string) bool {
-	// Case-insensitive path prefix matching
-	return strings.HasPrefix(
-		strings.ToLower(requestPath),
-		strings.ToLower(servicePath),
-	)
+	return app
 }
 
-// prepareHandlers applies interceptors and CORS to all handlers and
-// sorts registrations by path specificity. It runs exactly once, so
-// calling Handle and/or HandleAPIGateway multiple times is safe.
+// prepareHandlers applies interceptors, CORS and panic recovery to all
+// handlers and builds the router. It runs exactly once, so calling
+// Handle and/or HandleAPIGateway multiple times is safe.
 //
 // If app-level interceptors are configured and a handler cannot
 // receive them, prepareHandlers panics rather than silently running
 // the handler without (for example) authentication. Apply interceptors
 // at handler creation with connect.WithInterceptors, or register the
-// handler with WithPlainService if it should not receive them.
+// handler with WithPlainService if it should not receive them. It also
+// panics if registration paths are invalid or conflict, and, with
+// WithAuthRuleValidation, if a method has no authorization rule.
 func (a *App) prepareHandlers() {
 	a.prepareOnce.Do(func() {
-		// Sort by path length (descending) so that more specific
-		// handlers are matched before catch-all handlers.
-		sort.SliceStable(a.registrations, func(i, j int) bool {
-			return len(a.registrations[i].Path) > len(a.registrations[j].Path)
-		})
+		if a.validateAuthRules {
+			a.validateRegisteredAuthRules()
+		}
 
 		// Resolve the telemetry interceptor once. Telemetry is
 		// best-effort: unlike auth interceptors, a missing telemetry
@@ -233,14 +254,81 @@ func (a *App) prepareHandlers() {
 				handler = cors.Middleware(*a.corsOptions, handler)
 			}
 
+			// Recover panics outermost, so that error responses still
+			// carry CORS headers and the client can read them.
+			handler = a.recoverPanics(reg.Path, handler)
+
 			a.registrations[i].Handler = handler
 		}
+
+		a.router = newRouter(a.registrations)
+		a.prepareFallbacks()
 	})
 }
 
-// processRequest handles an HTTP request and returns the result.
-// The context is currently unused but may be needed for future extensions.
-func (a *App) processRequest(_ context.Context, req *http.Request, path string) (*lambda.Response, error) {
+// processRequest handles an HTTP request and returns the result. ctx
+// is the request context; see serveBuffered for how its deadline is
+// enforced.
+func (a *App) processRequest(ctx context.Context, req *http.Request) (*lambda.Response, error) {
+	w := a.serveBuffered(ctx, req)
+
+	resp, err := w.GetLambdaResponse()
+	if err != nil {
+		a.logger.Error("Failed to get lambda response", "error", err)
+
+		return nil, fmt.Errorf("failed to get lambda response: %w", err)
+	}
+
+	if a.maxResponseSize > 0 {
+		if size := resp.EncodedSize(a.maxResponseSize); size > a.maxResponseSize {
+			return a.oversizedResponse(req, w, size)
+		}
+	}
+
+	return &resp, nil
+}
+
+// oversizedResponse replaces a response that exceeds the Lambda payload
+// limit, using the OversizedResponseHandler if one is configured and an
+// error in the caller's protocol otherwise.
+func (a *App) oversizedResponse(req *http.Request, w *lambda.ProxyResponseWriter, size int) (*lambda.Response, error) {
+	a.logger.Warn("Lambda response exceeds size limit",
+		"path", req.URL.Path,
+		"size", size,
+		"limit", a.maxResponseSize)
+
+	if a.oversizedResponseHandler != nil {
+		rw := lambda.NewProxyResponseWriter(a.binaryMediaTypes...)
+		a.oversizedResponseHandler(rw, req, w.Header().Clone(), w.Body())
+
+		resp, err := rw.GetLambdaResponse()
+
+		switch {
+		case err != nil:
+			a.logger.Error("Oversized response handler failed", "error", err)
+		case resp.EncodedSize(a.maxResponseSize) > a.maxResponseSize:
+			a.logger.Error("Oversized response handler returned a response that is too large")
+		default:
+			return &resp, nil
+		}
+	}
+
+	ew := lambda.NewProxyResponseWriter(a.binaryMediaTypes...)
+	writeError(ew, req, connect.CodeResourceExhausted, http.StatusRequestEntityTooLarge,
+		"response exceeds the maximum size")
+
+	resp, err := ew.GetLambdaResponse()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lambda response: %w", err)
+	}
+
+	return &resp, nil
+}
+
+// dispatch routes a request to the matching registration and serves
+// it. It is shared by the Lambda entry points and HTTPHandler, so a
+// locally served App behaves exactly like the deployed one.
+func (a *App) dispatch(w http.ResponseWriter, req *http.Request) {
 	a.logger.Debug("GeneratedHTTPRequest",
 		"Method", req.Method,
 		"host", req.Host,
@@ -248,31 +336,19 @@ func (a *App) processRequest(_ context.Context, req *http.Request, path string)
 		"Headers", redactHeaders(req.Header),
 	)
 
-	w := lambda.NewProxyResponseWriter()
-
-	// Registrations are sorted by path specificity in prepareHandlers.
-	// Find and execute handler.
-	for _, reg := range a.registrations {
-		a.logger.Debug("Handle:", "reg.Path", reg.Path)
+	setRequestIDHeader(w, req)
 
-		if a.pathMatches(path, reg.Path) {
-			reg.Handler.ServeHTTP(w, req)
+	fallback, pattern := a.router.Handler(req)
+	req = a.withLogContext(req, pattern)
+	req = a.withAuditContext(req)
 
-			resp, err := w.GetLambdaResponse()
-			if err != nil {
-				a.logger.Error("Failed to get lambda response", "error", err)
-
-				return nil, fmt.Errorf("failed to get lambda response: %w", err)
-			}
+	if pattern == "" {
+		a.serveFallback(w, req, fallback)
 
-			return &resp, nil
-		}
+		return
 	}
 
-	return &lambda.Response{
-		StatusCode: http.StatusNotFound,
-		Body:       "Not found",
-	}, nil
+	a.router.ServeHTTP(w, req)
 }
 
 // internalServerErrorBody is the generic body returned for unexpected
@@ -296,33 +372,47 @@ func redactHeaders(h http.Header) http.Header {
 	return redacted
 }
 
-// Handle returns a Lambda handler function for ALB events.
-func (a *App) Handle() func(context.Context, events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
-	a.prepareHandlers()
+// internalServerErrorResponse returns the generic response used for
+// unexpected failures in the Lambda entry points.
+func internalServerErrorResponse() lambda.Response {
+	return lambda.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       internalServerErrorBody,
+	}
+}
 
-	return func(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
-		// Convert to our internal request type
-		request := lambda.FromALBRequest(event)
+// handleRequest converts a generic Lambda request to an HTTP request,
+// serves it, and returns the Lambda response. Failures are logged and
+// turned into a generic 500 response.
+func (a *App) handleRequest(ctx context.Context, request lambda.Request) lambda.Response {
+	ctx, cancel := a.withDeadline(withLambdaRequestInfo(ctx, request))
+	defer cancel()
 
-		req, err := lambda.AWSRequestToHTTPRequest(ctx, request)
-		if err != nil {
-			a.logger.Error("Failed to create HTTP request", "error", err)
+	req, err := lambda.AWSRequestToHTTPRequest(ctx, request)
+	if err != nil {
+		a.logger.Error("Failed to create HTTP request", "error", err)
 
-			return events.ALBTargetGroupResponse{
-				StatusCode: http.StatusInternalServerError,
-				Body:       internalServerErrorBody,
-			}, nil
-		}
+		return internalServerErrorResponse()
+	}
 
-		resp, err := a.processRequest(ctx, req, request.Path)
-		if err != nil {
-			a.logger.Error("Failed to process request", "error", err)
+	req = withRequestID(req)
 
-			return events.ALBTargetGroupResponse{
-				StatusCode: http.StatusInternalServerError,
-				Body:       internalServerErrorBody,
-			}, nil
-		}
+	resp, err := a.processRequest(ctx, req)
+	if err != nil {
+		a.logger.Error("Failed to process request", "error", err)
+
+		return internalServerErrorResponse()
+	}
+
+	return *resp
+}
+
+// Handle returns a Lambda handler function for ALB events.
+func (a *App) Handle() func(context.Context, events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+	a.prepareHandlers()
+
+	return func(ctx context.Context, event events.ALBTargetGroupRequest) (events.ALBTargetGroupResponse, error) {
+		resp := a.handleRequest(ctx, lambda.FromALBRequest(event))
 
 		// Convert to ALB response
 		return events.ALBTargetGroupResponse{
@@ -335,42 +425,247 @@ func (a *App) Handle() func(context.Context, events.ALBTargetGroupRequest) (even
 	}
 }
 
-// HandleAPIGateway returns a Lambda handler function for API Gateway events.
+// HandleAPIGateway returns a Lambda handler function for API Gateway
+// HTTP API (v2) events.
+//
+// Incoming cookies are folded into the Cookie header, and outgoing
+// Set-Cookie headers are returned in the response's cookies field,
+// since the v2 payload format does not support repeated headers.
 func (a *App) HandleAPIGateway() func(context.Context, events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 	a.prepareHandlers()
 
 	return func(ctx context.Context, event events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
-		// Convert to our internal request type
-		request := lambda.FromAPIGatewayRequest(event)
+		resp := a.handleRequest(ctx, lambda.FromAPIGatewayRequest(event))
+
+		// The v2 payload format only supports single-value headers;
+		// Set-Cookie headers must be returned in the cookies field.
+		headers, cookies := lambda.SplitHeaders(resp.MultiValueHeaders)
+
+		// Convert to API Gateway response
+		return events.APIGatewayV2HTTPResponse{
+			StatusCode:      resp.StatusCode,
+			Headers:         headers,
+			Body:            resp.Body,
+			IsBase64Encoded: resp.IsBase64Encoded,
+			Cookies:         cookies,
+		}, nil
+	}
+}
+
+// HandleAPIGatewayV1 returns a Lambda handler function for API Gateway
+// REST API (v1) proxy events.
+//
+// Multi-value headers and query strings, stage-prefixed paths and
+// base64-encoded bodies are supported, so services behind an existing
+// REST API can be served without first migrating the gateway:
+//
+//	lambda.Start(app.HandleAPIGatewayV1())
+func (a *App) HandleAPIGatewayV1() func(context.Context, events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	a.prepareHandlers()
+
+	return func(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+		resp := a.handleRequest(ctx, lambda.FromAPIGatewayV1Request(event))
+
+		// Convert to API Gateway REST API response
+		return events.APIGatewayProxyResponse{
+			StatusCode:        resp.StatusCode,
+			Headers:           resp.Headers,
+			MultiValueHeaders: resp.MultiValueHeaders,
+			Body:              resp.Body,
+			IsBase64Encoded:   resp.IsBase64Encoded,
+		}, nil
+	}
+}
+
+// HandleFunctionURL returns a Lambda handler function for Lambda
+// Function URL events using the default BUFFERED invoke mode.
+//
+//	lambda.Start(app.HandleFunctionURL())
+//
+// Use HandleFunctionURLStreaming for Function URLs configured with the
+// RESPONSE_STREAM invoke mode.
+func (a *App) HandleFunctionURL() func(context.Context, events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+	a.prepareHandlers()
+
+	return func(ctx context.Context, event events.LambdaFunctionURLRequest) (events.LambdaFunctionURLResponse, error) {
+		resp := a.handleRequest(ctx, lambda.FromFunctionURLRequest(event))
+
+		// Function URL responses only support single-value headers;
+		// cookies are returned separately.
+		headers, cookies := lambda.SplitHeaders(resp.MultiValueHeaders)
+
+		return events.LambdaFunctionURLResponse{
+			StatusCode:      resp.StatusCode,
+			Headers:         headers,
+			Body:            resp.Body,
+			IsBase64Encoded: resp.IsBase64Encoded,
+			Cookies:         cookies,
+		}, nil
+	}
+}
+
+// HandleFunctionURLStreaming returns a Lambda handler function for
+// Lambda Function URLs configured with the RESPONSE_STREAM invoke mode
+// (InvokeWithResponseStream).
+//
+// The response is written straight to the Lambda response stream as
+// the handler produces it instead of being buffered, which makes
+// Connect server-streaming RPCs and large responses possible. The
+// status code and headers are sent when the handler first writes or
+// flushes the response.
+//
+// Response streaming requires the provided.al2/provided.al2023 runtime,
+// or building with the lambda.norpc tag:
+//
+//	lambda.Start(app.HandleFunctionURLStreaming())
+func (a *App) HandleFunctionURLStreaming() func(context.Context, events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+	a.prepareHandlers()
+
+	return func(ctx context.Context, event events.LambdaFunctionURLRequest) (*events.LambdaFunctionURLStreamingResponse, error) {
+		request := lambda.FromFunctionURLRequest(event)
+
+		// The context outlives this function: it is cancelled when the
+		// handler returns, after the body has been streamed.
+		ctx, cancel := a.withDeadline(withLambdaRequestInfo(ctx, request))
 
 		req, err := lambda.AWSRequestToHTTPRequest(ctx, request)
 		if err != nil {
+			cancel()
 			a.logger.Error("Failed to create HTTP request", "error", err)
 
-			return events.APIGatewayV2HTTPResponse{
+			return &events.LambdaFunctionURLStreamingResponse{
 				StatusCode: http.StatusInternalServerError,
-				Body:       internalServerErrorBody,
+				Body:       strings.NewReader(internalServerErrorBody),
 			}, nil
 		}
 
-		resp, err := a.processRequest(ctx, req, request.Path)
-		if err != nil {
-			a.logger.Error("Failed to process request", "error", err)
+		req = withRequestID(req)
 
-			return events.APIGatewayV2HTTPResponse{
-				StatusCode: http.StatusInternalServerError,
-				Body:       internalServerErrorBody,
-			}, nil
+		w := lambda.NewStreamingResponseWriter()
+
+		go func() {
+			defer cancel()
+			defer func() { _ = w.Close() }()
+
+			a.dispatch(w, req)
+		}()
+
+		// Wait for the handler to commit the status code and headers;
+		// the body is streamed by the runtime after we return.
+		if !a.awaitCommit(ctx, w) {
+			// Closing the body makes the abandoned handler's writes fail.
+			_ = w.Body().Close()
+
+			return a.deadlineExceededStreamingResponse(ctx, req), nil
 		}
 
-		// Convert to API Gateway response
-		return events.APIGatewayV2HTTPResponse{
-			StatusCode:        resp.StatusCode,
-			Headers:           resp.Headers,
-			MultiValueHeaders: resp.MultiValueHeaders,
-			Body:              resp.Body,
-			IsBase64Encoded:   resp.IsBase64Encoded,
-			Cookies:           resp.Cookies,
+		headers, cookies := lambda.SplitHeaders(w.CommittedHeader())
+
+		return &events.LambdaFunctionURLStreamingResponse{
+			StatusCode: w.StatusCode(),
+			Headers:    headers,
+			Cookies:    cookies,
+			Body:       w.Body(),
 		}, nil
 	}
 }
+
+// awaitCommit waits until the handler has committed the response
+// headers. It reports false if the deadline margin is configured and
+// ctx expired first.
+func (a *App) awaitCommit(ctx context.Context, w *lambda.StreamingResponseWriter) bool {
+	if a.deadlineMargin <= 0 {
+		<-w.Ready()
+
+		return true
+	}
+
+	select {
+	case <-w.Ready():
+		return true
+	case <-ctx.Done():
+	}
+
+	select {
+	case <-w.Ready():
+		return true
+	default:
+		return false
+	}
+}
+
+// deadlineExceededStreamingResponse answers a streaming request whose
+// handler did not start its response before the deadline.
+func (a *App) deadlineExceededStreamingResponse(
+	ctx context.Context, req *http.Request,
+) *events.LambdaFunctionURLStreamingResponse {
+	a.logger.WarnContext(ctx, "Handler did not respond before the Lambda deadline",
+		"path", req.URL.Path,
+		"margin", a.deadlineMargin)
+
+	w := lambda.NewProxyResponseWriter()
+	writeError(w, req, connect.CodeDeadlineExceeded, http.StatusGatewayTimeout, deadlineExceededMessage)
+
+	headers, cookies := lambda.SplitHeaders(w.Header())
+
+	return &events.LambdaFunctionURLStreamingResponse{
+		StatusCode: w.StatusCode(),
+		Headers:    headers,
+		Cookies:    cookies,
+		Body:       bytes.NewReader(w.Body()),
+	}
+}
+
+// HandleAny returns a Lambda handler function that accepts any of the
+// supported HTTP events — ALB, API Gateway REST API (v1), API Gateway
+// HTTP API (v2) and (buffered) Function URL — and answers with the
+// matching response shape.
+//
+// The event type is detected per invocation from the payload (see the
+// requestContext.elb and version fields), so the same binary can sit
+// behind any trigger, for example while migrating between them:
+//
+//	lambda.Start(app.HandleAny())
+//
+// Payloads that are not recognised as HTTP events are rejected with an
+// error rather than being served as empty requests.
+func (a *App) HandleAny() func(context.Context, json.RawMessage) (any, error) {
+	handleALB := a.Handle()
+	handleAPIGatewayV1 := a.HandleAPIGatewayV1()
+	handleAPIGateway := a.HandleAPIGateway()
+	handleFunctionURL := a.HandleFunctionURL()
+
+	return func(ctx context.Context, payload json.RawMessage) (any, error) {
+		eventType, err := lambda.DetectEventType(payload)
+		if err != nil {
+			a.logger.Error("Unsupported lambda event", "error", err)
+
+			return nil, fmt.Errorf("unsupported lambda event: %w", err)
+		}
+
+		switch eventType {
+		case lambda.EventTypeALB:
+			return invokeWith(ctx, payload, handleALB)
+		case lambda.EventTypeAPIGatewayV1:
+			return invokeWith(ctx, payload, handleAPIGatewayV1)
+		case lambda.EventTypeAPIGatewayV2:
+			return invokeWith(ctx, payload, handleAPIGateway)
+		case lambda.EventTypeFunctionURL:
+			return invokeWith(ctx, payload, handleFunctionURL)
+		case lambda.EventTypeUnknown:
+		}
+
+		return nil, fmt.Errorf("unsupported lambda event type %s", eventType)
+	}
+}
+
+// invokeWith decodes payload into the event type expected by handler
+// and calls it.
+func invokeWith[E, R any](ctx context.Context, payload json.RawMessage, handler func(context.Context, E) (R, error)) (any, error) {
+	var event E
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to decode lambda event: %w", err)
+	}
+
+	return handler(ctx, event)
+}
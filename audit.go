@@ -0,0 +1,50 @@
+package dindenault
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// WithAuditSink records every authentication and authorization decision
+// made while serving requests to sink: those of AuthInterceptors,
+// PathInterceptors, AuthRuleInterceptors, path permission services, MCP
+// tools, navigaid.RequirePermission and the Authorize helpers. Events
+// carry the subject, organisation, procedure, required permissions,
+// decision and reason, client IP and request ID.
+//
+// Allowed decisions are recorded for every request; wrap the sink with
+// navigaid.SampleAllowed to keep a fraction of them:
+//
+//	app := dindenault.New(logger,
+//	    dindenault.WithAuditSink(navigaid.SampleAllowed(
+//	        navigaid.NewJSONLinesAuditSink(os.Stdout), 0.01)),
+//	)
+func WithAuditSink(sink navigaid.AuditSink) Option {
+	return func(a *App) {
+		a.auditSink = sink
+	}
+}
+
+// withAuditContext adds the audit sink to the request context.
+func (a *App) withAuditContext(req *http.Request) *http.Request {
+	if a.auditSink == nil {
+		return req
+	}
+
+	return req.WithContext(navigaid.ContextWithAudit(req.Context(), a.auditSink, req))
+}
+
+// recordDecision records an authorization decision to the audit sink
+// of ctx.
+func recordDecision(ctx context.Context, allowed bool, event navigaid.AuditEvent) {
+	event.Type = navigaid.AuditAuthorization
+	event.Decision = navigaid.AuditAllow
+
+	if !allowed {
+		event.Decision = navigaid.AuditDeny
+	}
+
+	navigaid.RecordAudit(ctx, event)
+}
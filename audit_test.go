@@ -0,0 +1,161 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+func TestWithAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	const procedure = "/test.v1.ArticleService/Get"
+
+	connectPath, connectHandler := procedure, connect.NewUnaryHandler(procedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(
+			navigaid.ConnectInterceptor(slog.Default(), readerJWKS()),
+			dindenault.PathInterceptors(slog.Default(), []dindenault.PathPermissionConfig{
+				{PathPrefix: procedure, Permissions: []string{"articles:read"}},
+			}),
+		))
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithAuditSink(navigaid.NewJSONLinesAuditSink(&buf)),
+		dindenault.WithService(connectPath, connectHandler),
+		dindenault.WithPathPermissionService("/api/", readerJWKS(), http.NotFoundHandler(),
+			[]dindenault.PathPermissionConfig{
+				{PathPrefix: "/api/articles", Permissions: []string{"articles:read"}},
+				{PathPrefix: "/api/status", Public: true},
+			}),
+	)
+
+	server := httptest.NewServer(app.HTTPHandler())
+	defer server.Close()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set("Authorization", "Bearer reader")
+	req.Header().Set("X-Request-Id", "audit-request")
+
+	if _, err := client.CallUnary(context.Background(), req); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+"/api/articles/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	httpReq.Header.Set("Authorization", "Bearer other")
+
+	resp, err := server.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	httpReq, err = http.NewRequestWithContext(context.Background(), http.MethodGet, server.URL+"/api/status", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = server.Client().Do(httpReq)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	var events []navigaid.AuditEvent
+
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var event navigaid.AuditEvent
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Invalid audit line %q: %v", line, err)
+		}
+
+		events = append(events, event)
+	}
+
+	expected := []struct {
+		source, decision, procedure string
+	}{
+		{"ConnectInterceptor", navigaid.AuditAllow, procedure},
+		{"PathInterceptors", navigaid.AuditAllow, procedure},
+		{"HTTPMiddleware", navigaid.AuditAllow, "/api/articles/1"},
+		{"PathPermissionHandler", navigaid.AuditDeny, "/api/articles/1"},
+		{"PathPermissionHandler", navigaid.AuditAllow, "/api/status"},
+	}
+
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), events)
+	}
+
+	for i, want := range expected {
+		event := events[i]
+		if event.Source != want.source || event.Decision != want.decision || event.Procedure != want.procedure {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want, event)
+		}
+
+		if event.ClientIP == "" || (event.Org != "acme" && event.Reason != "public") {
+			t.Errorf("Event %d: missing caller details: %+v", i, event)
+		}
+	}
+
+	if events[1].RequestID != "audit-request" || events[1].Permissions[0] != "articles:read" {
+		t.Errorf("Unexpected Connect event %+v", events[1])
+	}
+
+	if events[3].Reason != "lacks permission articles:read" {
+		t.Errorf("Unexpected denial reason %q", events[3].Reason)
+	}
+
+	if events[4].Reason != "public" || events[4].Org != "" {
+		t.Errorf("Unexpected public path event %+v", events[4])
+	}
+}
+
+func TestAuthorizeAuditsUnauthenticated(t *testing.T) {
+	var events []navigaid.AuditEvent
+
+	sink := navigaid.AuditSinkFunc(func(_ context.Context, event navigaid.AuditEvent) {
+		events = append(events, event)
+	})
+
+	ctx := navigaid.ContextWithAudit(context.Background(), sink, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if _, err := dindenault.AuthorizeWithDetails(ctx, "articles:read"); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("Expected unauthenticated, got %v", err)
+	}
+
+	if _, err := dindenault.AuthorizeRequirement(ctx, navigaid.Permission("articles:read")); connect.CodeOf(err) != connect.CodeUnauthenticated {
+		t.Errorf("Expected unauthenticated, got %v", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %+v", events)
+	}
+
+	for i, source := range []string{"AuthorizeWithDetails", "AuthorizeRequirement"} {
+		event := events[i]
+		if event.Source != source || event.Decision != navigaid.AuditDeny || event.Reason != "not authenticated" {
+			t.Errorf("Event %d: unexpected %+v", i, event)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package dindenault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"connectrpc.com/connect"
@@ -94,35 +95,100 @@ func checkUserPermission(claims navigaid.Claims, permission string) bool {
 func AuthorizeWithDetails(ctx context.Context, permission string) (*AuthResult, error) {
 	auth, err := navigaid.GetAuth(ctx)
 	if err != nil {
+		event := navigaid.AuditEvent{Source: "AuthorizeWithDetails", Reason: "not authenticated"}
+		if permission != "" {
+			event.Permissions = []string{permission}
+		}
+
+		recordDecision(ctx, false, event)
+
 		return nil, connect.NewError(connect.CodeUnauthenticated,
 			fmt.Errorf("failed to get authorization: %w", err))
 	}
 
 	// Verify permission if specified
 	if !checkUserPermission(auth.Claims, permission) {
+		recordDecision(ctx, false, navigaid.AuditEvent{
+			Source:      "AuthorizeWithDetails",
+			Permissions: []string{permission},
+			Reason:      "lacks permission " + permission,
+		})
+
 		return nil, connect.NewError(connect.CodePermissionDenied,
 			fmt.Errorf("missing required permission: %s", permission))
 	}
 
+	if permission != "" {
+		recordDecision(ctx, true, navigaid.AuditEvent{
+			Source:      "AuthorizeWithDetails",
+			Permissions: []string{permission},
+			Reason:      "has permission " + permission,
+		})
+	}
+
+	return newAuthResult(auth.Claims), nil
+}
+
+// AuthorizeRequirement is AuthorizeWithDetails with a requirement
+// expression instead of a single permission. The permission_denied
+// error explains which part of the requirement is not met.
+//
+// Example:
+//
+//	editor := navigaid.MustParseRequirement("(articles:edit or group(admins)) and not group(suspended)")
+//
+//	authResult, err := dindenault.AuthorizeRequirement(ctx, editor)
+//	if err != nil {
+//	    return nil, err // Already formatted as connect.Error
+//	}
+func AuthorizeRequirement(ctx context.Context, requirement navigaid.Requirement) (*AuthResult, error) {
+	event := navigaid.AuditEvent{Source: "AuthorizeRequirement", Reason: "requirement met"}
+	if requirement != nil {
+		event.Permissions = []string{requirement.String()}
+	}
+
+	auth, err := navigaid.GetAuth(ctx)
+	if err != nil {
+		event.Reason = "not authenticated"
+		recordDecision(ctx, false, event)
+
+		return nil, connect.NewError(connect.CodeUnauthenticated,
+			fmt.Errorf("failed to get authorization: %w", err))
+	}
+
+	var requirementErr *navigaid.RequirementError
+
+	if errors.As(navigaid.CheckRequirement(requirement, navigaid.Caller{Claims: auth.Claims}), &requirementErr) {
+		event.Reason = requirementErr.Reason
+		recordDecision(ctx, false, event)
+
+		return nil, connect.NewError(connect.CodePermissionDenied, requirementErr)
+	}
+
+	recordDecision(ctx, true, event)
+
+	return newAuthResult(auth.Claims), nil
+}
+
+// newAuthResult copies the details of the claims into an AuthResult.
+func newAuthResult(claims navigaid.Claims) *AuthResult {
 	// Copy unit permissions with their unit context preserved
-	unitPermissions := make(map[string][]string, len(auth.Claims.Permissions.Units))
-	for unit, unitPerms := range auth.Claims.Permissions.Units {
+	unitPermissions := make(map[string][]string, len(claims.Permissions.Units))
+	for unit, unitPerms := range claims.Permissions.Units {
 		unitPermissions[unit] = append([]string(nil), unitPerms...)
 	}
 
 	// Create and return the result with all available user information
-	result := &AuthResult{
-		Organization:    auth.Claims.Org,
-		GivenName:       auth.Claims.Userinfo.GivenName,
-		FamilyName:      auth.Claims.Userinfo.FamilyName,
-		Email:           auth.Claims.Userinfo.Email,
-		UserID:          auth.Claims.Subject,
-		Permissions:     append([]string(nil), auth.Claims.Permissions.Org...),
+	return &AuthResult{
+		Organization:    claims.Org,
+		GivenName:       claims.Userinfo.GivenName,
+		FamilyName:      claims.Userinfo.FamilyName,
+		Email:           claims.Userinfo.Email,
+		UserID:          claims.Subject,
+		Permissions:     append([]string(nil), claims.Permissions.Org...),
 		UnitPermissions: unitPermissions,
-		Groups:          auth.Claims.Groups,
+		Groups:          claims.Groups,
 	}
-
-	return result, nil
 }
 
 // GetAuthResultFromContext retrieves authentication details from the context
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"connectrpc.com/connect"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -48,6 +49,24 @@ func TestAuthorizeWithDetails(t *testing.T) {
 	assert.Contains(t, err.Error(), "missing required permission: admin:manage")
 }
 
+func TestAuthorizeRequirement(t *testing.T) {
+	ctx := createAuthContext()
+
+	result, err := da.AuthorizeRequirement(ctx,
+		navigaid.MustParseRequirement("(admin:manage or group(editors)) and not group(suspended)"))
+	require.NoError(t, err)
+	assert.Equal(t, "test-org", result.Organization)
+
+	result, err = da.AuthorizeRequirement(ctx, navigaid.MustParseRequirement("content:read and not group(writers)"))
+	require.Error(t, err)
+	assert.Nil(t, result)
+	assert.Equal(t, connect.CodePermissionDenied, connect.CodeOf(err))
+	assert.Contains(t, err.Error(), "member of group writers")
+
+	_, err = da.AuthorizeRequirement(context.Background(), navigaid.Permission("content:read"))
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+}
+
 func TestGetAuthResultFromContext(t *testing.T) {
 	// Create a mock context with auth info
 	ctx := createAuthContext()
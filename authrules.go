@@ -0,0 +1,496 @@
+package dindenault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// AuthRuleFieldNumber is the field number of the (dindenault.auth)
+// method option declared in proto/dindenault/auth.proto.
+const AuthRuleFieldNumber protowire.Number = 51470
+
+// AuthRule is the authorization rule of a method, declared with the
+// (dindenault.auth) method option.
+type AuthRule struct {
+	// Permissions the caller must all have, in the organisation or,
+	// with UnitField, in the unit named by the request.
+	Permissions []string
+	// Public methods can be called without authentication.
+	Public bool
+	// UnitField is the name of a string field of the request message
+	// holding the unit in which the permissions are required, or a
+	// dotted path to one; see UnitField.
+	UnitField string
+	// Authenticated methods can be called by any authenticated caller.
+	Authenticated bool
+}
+
+// empty reports whether the rule sets nothing. An empty
+// (dindenault.auth) option is most likely a mistake, so it is rejected
+// rather than read as "any authenticated caller"; use Authenticated.
+func (r AuthRule) empty() bool {
+	return !r.Public && !r.Authenticated && len(r.Permissions) == 0 && r.UnitField == ""
+}
+
+// conflicting reports whether the rule is public but also requires
+// authentication, which leaves it unclear whether callers must log in.
+func (r AuthRule) conflicting() bool {
+	return r.Public && (r.Authenticated || len(r.Permissions) > 0 || r.UnitField != "")
+}
+
+// MethodAuthRule returns the (dindenault.auth) option of a method. It
+// reports false if the method has no such option.
+//
+// The option is read from the wire format of the method options, so it
+// is found whether or not Go code was generated for auth.proto.
+func MethodAuthRule(method protoreflect.MethodDescriptor) (AuthRule, bool, error) {
+	options := method.Options()
+	if options == nil {
+		return AuthRule{}, false, nil
+	}
+
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(options)
+	if err != nil {
+		return AuthRule{}, false, fmt.Errorf("failed to marshal options of %s: %w", method.FullName(), err)
+	}
+
+	var (
+		rule  AuthRule
+		found bool
+	)
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return AuthRule{}, false, fmt.Errorf("invalid options of %s: %w", method.FullName(), protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		if num == AuthRuleFieldNumber && typ == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return AuthRule{}, false, fmt.Errorf("invalid auth rule of %s: %w", method.FullName(), protowire.ParseError(n))
+			}
+
+			// Repeated occurrences of a message field are merged.
+			if err := rule.unmarshal(value); err != nil {
+				return AuthRule{}, false, fmt.Errorf("invalid auth rule of %s: %w", method.FullName(), err)
+			}
+
+			found = true
+			b = b[n:]
+
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return AuthRule{}, false, fmt.Errorf("invalid options of %s: %w", method.FullName(), protowire.ParseError(n))
+		}
+
+		b = b[n:]
+	}
+
+	return rule, found, nil
+}
+
+// unmarshal merges the wire format of a dindenault.AuthRule message
+// into r.
+func (r *AuthRule) unmarshal(b []byte) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		b = b[n:]
+
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			var permission string
+
+			permission, n = protowire.ConsumeString(b)
+			r.Permissions = append(r.Permissions, permission)
+		case num == 2 && typ == protowire.VarintType:
+			var public uint64
+
+			public, n = protowire.ConsumeVarint(b)
+			r.Public = public != 0
+		case num == 3 && typ == protowire.BytesType:
+			r.UnitField, n = protowire.ConsumeString(b)
+		case num == 4 && typ == protowire.VarintType:
+			var authenticated uint64
+
+			authenticated, n = protowire.ConsumeVarint(b)
+			r.Authenticated = authenticated != 0
+		default:
+			// Skip unknown fields.
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// ValidateAuthRules checks that every method of the given services has
+// a valid, non-empty (dindenault.auth) option, and returns an error
+// listing the methods that do not. Call it at startup, or in a test, so that a new
+// method cannot be deployed without an authorization rule:
+//
+//	err := dindenault.ValidateAuthRules(articlev1.File_article_v1_service_proto.Services().Get(0))
+func ValidateAuthRules(services ...protoreflect.ServiceDescriptor) error {
+	var problems []string
+
+	for _, service := range services {
+		methods := service.Methods()
+
+		for i := range methods.Len() {
+			method := methods.Get(i)
+
+			rule, ok, err := MethodAuthRule(method)
+
+			switch {
+			case err != nil:
+				problems = append(problems, err.Error())
+			case !ok:
+				problems = append(problems, string(method.FullName())+" has no (dindenault.auth) option")
+			case rule.empty():
+				problems = append(problems, string(method.FullName())+
+					" has an empty (dindenault.auth) option; set authenticated, permissions or public")
+			case rule.conflicting():
+				problems = append(problems, string(method.FullName())+" is public but requires authentication")
+			case rule.UnitField != "":
+				if _, err := resolveUnitField(method.Input(), rule.UnitField); err != nil {
+					problems = append(problems, string(method.FullName())+": "+err.Error())
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid authorization rules:\n  %s", strings.Join(problems, "\n  "))
+	}
+
+	return nil
+}
+
+// WithAuthRuleValidation makes the App validate the (dindenault.auth)
+// options of the registered Connect services when it starts, like
+// ValidateAuthRules, and panic if a method has no valid rule. Services
+// are looked up in protoregistry.GlobalFiles, where generated code
+// registers them.
+func WithAuthRuleValidation() Option {
+	return func(a *App) {
+		a.validateAuthRules = true
+	}
+}
+
+// validateRegisteredAuthRules implements WithAuthRuleValidation.
+func (a *App) validateRegisteredAuthRules() {
+	var services []protoreflect.ServiceDescriptor
+
+	for _, name := range a.ServiceNames() {
+		desc, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+		if err != nil {
+			panic(fmt.Sprintf("dindenault: cannot validate authorization rules of %s: %v", name, err))
+		}
+
+		service, ok := desc.(protoreflect.ServiceDescriptor)
+		if !ok {
+			panic(fmt.Sprintf("dindenault: cannot validate authorization rules of %s: not a service", name))
+		}
+
+		services = append(services, service)
+	}
+
+	if err := ValidateAuthRules(services...); err != nil {
+		panic("dindenault: " + err.Error())
+	}
+}
+
+// AuthRuleInterceptors creates a Connect interceptor that enforces the
+// (dindenault.auth) option of each method, read from the method
+// descriptor in the request's Spec().Schema. See
+// proto/dindenault/auth.proto for the option.
+//
+//   - Public methods pass through without authentication.
+//   - Other methods require an authenticated caller with all the
+//     listed permissions, in the organisation, or in the unit named by
+//     the request's unit_field. For client and bidi streams with a
+//     unit_field, every received message is checked, and nothing is
+//     sent before a received message has passed the check.
+//   - Authenticated methods require an authenticated caller.
+//   - Methods without the option or with an empty one, and handlers
+//     created without a schema, fail closed with CodePermissionDenied.
+//
+// If jwks is not nil the interceptor authenticates callers itself, so
+// public methods work without AuthInterceptors. With a nil jwks, apply
+// AuthInterceptors (or navigaid.ConnectInterceptor) first.
+//
+// Generated Connect handlers pass the schema automatically. Use
+// ValidateAuthRules or WithAuthRuleValidation to find methods without a
+// rule before they are called.
+//
+// Example:
+//
+//	path, handler := articlev1connect.NewArticleServiceHandler(
+//	    impl,
+//	    connect.WithInterceptors(
+//	        dindenault.AuthRuleInterceptors(logger, jwks),
+//	    ),
+//	)
+//
+//nolint:ireturn // Returning interface as intended by connect.Interceptor design
+func AuthRuleInterceptors(logger *slog.Logger, jwks *navigaid.JWKS) connect.Interceptor {
+	i := &authRuleInterceptor{logger: logger}
+
+	if jwks != nil {
+		i.auth = navigaid.ConnectInterceptor(logger, jwks)
+	}
+
+	return i
+}
+
+type authRuleInterceptor struct {
+	logger *slog.Logger
+	auth   connect.Interceptor
+
+	// rules caches the rule of each procedure.
+	rules sync.Map
+}
+
+// errNoAuthRule is returned for methods without an authorization rule.
+var errNoAuthRule = errors.New("method has no authorization rule")
+
+// errConflictingAuthRule is returned for methods whose rule is public
+// but also requires authentication.
+var errConflictingAuthRule = errors.New("method has a conflicting authorization rule")
+
+// rule returns the authorization rule for a call.
+func (i *authRuleInterceptor) rule(spec connect.Spec) (AuthRule, error) {
+	if cached, ok := i.rules.Load(spec.Procedure); ok {
+		return cached.(AuthRule), nil //nolint:forcetypeassert // only AuthRule values are stored
+	}
+
+	method, ok := spec.Schema.(protoreflect.MethodDescriptor)
+	if !ok {
+		i.logger.Error("no method descriptor for procedure; create the handler with connect.WithSchema",
+			"procedure", spec.Procedure)
+
+		return AuthRule{}, errNoAuthRule
+	}
+
+	rule, ok, err := MethodAuthRule(method)
+	if err != nil {
+		i.logger.Error("invalid authorization rule", "procedure", spec.Procedure, "error", err)
+
+		return AuthRule{}, errNoAuthRule
+	}
+
+	if !ok {
+		i.logger.Error("method has no (dindenault.auth) option", "procedure", spec.Procedure)
+
+		return AuthRule{}, errNoAuthRule
+	}
+
+	if rule.empty() {
+		i.logger.Error("method has an empty (dindenault.auth) option", "procedure", spec.Procedure)
+
+		return AuthRule{}, errNoAuthRule
+	}
+
+	if rule.conflicting() {
+		i.logger.Error("method has a (dindenault.auth) option that is public but requires authentication",
+			"procedure", spec.Procedure)
+
+		return AuthRule{}, errConflictingAuthRule
+	}
+
+	i.rules.Store(spec.Procedure, rule)
+
+	return rule, nil
+}
+
+func (i *authRuleInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	checked := connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		rule, err := i.rule(req.Spec())
+		if err != nil {
+			return nil, connect.NewError(connect.CodePermissionDenied, err)
+		}
+
+		if err := i.authorize(ctx, req.Spec(), rule, req.Any()); err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	})
+
+	authenticated := checked
+	if i.auth != nil {
+		authenticated = i.auth.WrapUnary(checked)
+	}
+
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		rule, err := i.rule(req.Spec())
+		if err != nil {
+			i.record(ctx, req.Spec(), false, AuthRule{}, "", err.Error())
+
+			return nil, connect.NewError(connect.CodePermissionDenied, err)
+		}
+
+		if rule.Public {
+			i.record(ctx, req.Spec(), true, rule, "", "public")
+
+			return next(ctx, req)
+		}
+
+		if _, err := navigaid.GetAuth(ctx); err == nil {
+			return checked(ctx, req)
+		}
+
+		return authenticated(ctx, req)
+	}
+}
+
+func (i *authRuleInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *authRuleInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	checked := connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		rule, err := i.rule(conn.Spec())
+		if err != nil {
+			return connect.NewError(connect.CodePermissionDenied, err)
+		}
+
+		// Without a unit field the permissions can be checked before
+		// any message is received.
+		if rule.UnitField == "" {
+			if err := i.authorize(ctx, conn.Spec(), rule, nil); err != nil {
+				return err
+			}
+
+			return next(ctx, conn)
+		}
+
+		return serveUnitChecked(ctx, next, conn, func(msg any) error {
+			return i.authorize(ctx, conn.Spec(), rule, msg)
+		})
+	})
+
+	authenticated := checked
+	if i.auth != nil {
+		authenticated = i.auth.WrapStreamingHandler(checked)
+	}
+
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		rule, err := i.rule(conn.Spec())
+		if err != nil {
+			i.record(ctx, conn.Spec(), false, AuthRule{}, "", err.Error())
+
+			return connect.NewError(connect.CodePermissionDenied, err)
+		}
+
+		if rule.Public {
+			i.record(ctx, conn.Spec(), true, rule, "", "public")
+
+			return next(ctx, conn)
+		}
+
+		if _, err := navigaid.GetAuth(ctx); err == nil {
+			return checked(ctx, conn)
+		}
+
+		return authenticated(ctx, conn)
+	}
+}
+
+// authorize checks the permissions of a rule against the authenticated
+// caller, and records the decision. msg is the request message, used to
+// find the unit.
+func (i *authRuleInterceptor) authorize(ctx context.Context, spec connect.Spec, rule AuthRule, msg any) error {
+	authInfo, err := navigaid.GetAuth(ctx)
+	if err != nil {
+		i.logger.Info("authentication required", "error", err)
+		i.record(ctx, spec, false, rule, "", "not authenticated")
+
+		return connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
+	}
+
+	if rule.UnitField == "" {
+		if !authInfo.Claims.HasPermissionsInOrganisation(rule.Permissions...) {
+			i.logger.Info("permission denied",
+				"procedure", spec.Procedure,
+				"permissions", rule.Permissions,
+				"user", authInfo.Claims.Subject,
+				"org", authInfo.Claims.Org)
+			i.record(ctx, spec, false, rule, "", "lacks permissions")
+
+			return connect.NewError(connect.CodePermissionDenied,
+				errors.New("missing required permission: "+strings.Join(rule.Permissions, ", ")))
+		}
+
+		i.record(ctx, spec, true, rule, "", "has required permissions")
+
+		return nil
+	}
+
+	unit, unitErr := extractUnit(ctx, i.logger, UnitField(rule.UnitField), msg)
+	if unitErr != nil {
+		i.record(ctx, spec, false, rule, "", unitErr.Message())
+
+		return unitErr
+	}
+
+	if !authInfo.Claims.HasPermissionsInUnit(unit, rule.Permissions...) {
+		i.logger.Info("permission denied for unit",
+			"procedure", spec.Procedure,
+			"unit", unit,
+			"permissions", rule.Permissions,
+			"user", authInfo.Claims.Subject,
+			"org", authInfo.Claims.Org)
+		i.record(ctx, spec, false, rule, unit, "lacks permissions in unit "+unit)
+
+		return unitPermissionDenied(unit, rule.Permissions)
+	}
+
+	i.record(ctx, spec, true, rule, unit, "has required permissions in unit "+unit)
+
+	return nil
+}
+
+// record records an authorization decision.
+func (i *authRuleInterceptor) record(
+	ctx context.Context, spec connect.Spec, allowed bool, rule AuthRule, unit, reason string,
+) {
+	recordDecision(ctx, allowed, navigaid.AuditEvent{
+		Source:      "AuthRuleInterceptors",
+		Procedure:   spec.Procedure,
+		Permissions: rule.Permissions,
+		Unit:        unit,
+		Reason:      reason,
+	})
+}
@@ -0,0 +1,307 @@
+package dindenault_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// authOption encodes a (dindenault.auth) method option.
+func authOption(permissions []string, public bool, unitField string) *descriptorpb.MethodOptions {
+	return encodeAuthOption(permissions, public, unitField, false)
+}
+
+// authenticatedOption encodes a (dindenault.auth) method option with
+// authenticated set.
+func authenticatedOption() *descriptorpb.MethodOptions {
+	return encodeAuthOption(nil, false, "", true)
+}
+
+func encodeAuthOption(permissions []string, public bool, unitField string, authenticated bool) *descriptorpb.MethodOptions {
+	var rule []byte
+
+	for _, permission := range permissions {
+		rule = protowire.AppendTag(rule, 1, protowire.BytesType)
+		rule = protowire.AppendString(rule, permission)
+	}
+
+	if public {
+		rule = protowire.AppendTag(rule, 2, protowire.VarintType)
+		rule = protowire.AppendVarint(rule, 1)
+	}
+
+	if unitField != "" {
+		rule = protowire.AppendTag(rule, 3, protowire.BytesType)
+		rule = protowire.AppendString(rule, unitField)
+	}
+
+	if authenticated {
+		rule = protowire.AppendTag(rule, 4, protowire.VarintType)
+		rule = protowire.AppendVarint(rule, 1)
+	}
+
+	b := protowire.AppendTag(nil, dindenault.AuthRuleFieldNumber, protowire.BytesType)
+	b = protowire.AppendBytes(b, rule)
+
+	options := &descriptorpb.MethodOptions{}
+	options.ProtoReflect().SetUnknown(b)
+
+	return options
+}
+
+// method returns the descriptor of a method returning Empty.
+func method(name, input string, options *descriptorpb.MethodOptions) *descriptorpb.MethodDescriptorProto {
+	return &descriptorpb.MethodDescriptorProto{
+		Name:       proto.String(name),
+		InputType:  proto.String(input),
+		OutputType: proto.String(".google.protobuf.Empty"),
+		Options:    options,
+	}
+}
+
+// articleService returns the descriptor of an ArticleService in the
+// given package whose methods are annotated with (dindenault.auth),
+// except Delete, followed by the extra methods.
+func articleService(t *testing.T, pkg string, extra ...*descriptorpb.MethodDescriptorProto) protoreflect.ServiceDescriptor {
+	t.Helper()
+
+	watch := method("Watch", ".google.protobuf.StringValue", authOption([]string{"articles:read"}, false, "value"))
+	watch.ServerStreaming = proto.Bool(true)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String(strings.ReplaceAll(pkg, ".", "/") + "/articles.proto"),
+		Package:    proto.String(pkg),
+		Syntax:     proto.String("proto3"),
+		Dependency: []string{"google/protobuf/empty.proto", "google/protobuf/wrappers.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("ArticleService"),
+			Method: append([]*descriptorpb.MethodDescriptorProto{
+				method("Get", ".google.protobuf.StringValue", authOption([]string{"articles:read"}, false, "value")),
+				method("List", ".google.protobuf.Empty", authOption([]string{"articles:read"}, false, "")),
+				method("Ping", ".google.protobuf.Empty", authOption(nil, true, "")),
+				method("Delete", ".google.protobuf.Empty", nil),
+				watch,
+			}, extra...),
+		}},
+	}, protoregistry.GlobalFiles)
+	if err != nil {
+		t.Fatalf("Failed to build descriptor: %v", err)
+	}
+
+	return file.Services().Get(0)
+}
+
+func TestMethodAuthRule(t *testing.T) {
+	methods := articleService(t, "test.v1").Methods()
+
+	rule, ok, err := dindenault.MethodAuthRule(methods.ByName("Get"))
+	if err != nil || !ok {
+		t.Fatalf("Expected a rule, got %v %v", ok, err)
+	}
+
+	if len(rule.Permissions) != 1 || rule.Permissions[0] != "articles:read" || rule.UnitField != "value" || rule.Public {
+		t.Errorf("Unexpected rule %+v", rule)
+	}
+
+	if rule, _, _ := dindenault.MethodAuthRule(methods.ByName("Ping")); !rule.Public {
+		t.Errorf("Expected a public rule, got %+v", rule)
+	}
+
+	if _, ok, err := dindenault.MethodAuthRule(methods.ByName("Delete")); ok || err != nil {
+		t.Errorf("Expected no rule, got %v %v", ok, err)
+	}
+}
+
+// ruleTestMethods are extra ArticleService methods: Count has an empty
+// rule, Stats only requires authentication and Export is public but
+// requires a permission.
+func ruleTestMethods() []*descriptorpb.MethodDescriptorProto {
+	return []*descriptorpb.MethodDescriptorProto{
+		method("Count", ".google.protobuf.Empty", authOption(nil, false, "")),
+		method("Stats", ".google.protobuf.Empty", authenticatedOption()),
+		method("Export", ".google.protobuf.Empty", authOption([]string{"articles:read"}, true, "")),
+	}
+}
+
+func TestValidateAuthRules(t *testing.T) {
+	err := dindenault.ValidateAuthRules(articleService(t, "test.rules.v1", ruleTestMethods()...))
+	if err == nil {
+		t.Fatal("Expected an error for the unannotated method")
+	}
+
+	if !strings.Contains(err.Error(), "test.rules.v1.ArticleService.Delete") {
+		t.Errorf("Expected the unannotated method to be listed, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "test.rules.v1.ArticleService.Count has an empty (dindenault.auth) option") {
+		t.Errorf("Expected the empty rule to be listed, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "test.rules.v1.ArticleService.Export is public but requires authentication") {
+		t.Errorf("Expected the conflicting rule to be listed, got %v", err)
+	}
+
+	for _, name := range []string{"Get", "List", "Ping", "Watch", "Stats"} {
+		if strings.Contains(err.Error(), "ArticleService."+name) {
+			t.Errorf("Annotated method %s should not be listed: %v", name, err)
+		}
+	}
+}
+
+func TestAuthRuleInterceptors(t *testing.T) {
+	methods := articleService(t, "test.v1", ruleTestMethods()...).Methods()
+
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(token string) (navigaid.Claims, error) {
+		claims := navigaid.Claims{Org: "acme"}
+
+		switch token {
+		case "org-reader":
+			claims.Permissions.Org = []string{"articles:read"}
+		case "unit-reader":
+			claims.Permissions.Units = map[string][]string{"unit-a": {"articles:read"}}
+		}
+
+		return claims, nil
+	})
+
+	interceptor := connect.WithInterceptors(dindenault.AuthRuleInterceptors(slog.Default(), jwks))
+
+	procedure := func(name protoreflect.Name) string {
+		return "/test.v1.ArticleService/" + string(name)
+	}
+
+	unary := func(name protoreflect.Name) (string, http.Handler) {
+		return procedure(name), connect.NewUnaryHandler(procedure(name),
+			func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[emptypb.Empty], error) {
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			},
+			connect.WithSchema(methods.ByName(name)), interceptor)
+	}
+
+	mux := http.NewServeMux()
+
+	for _, name := range []protoreflect.Name{"Get", "List", "Ping", "Delete", "Count", "Stats", "Export"} {
+		mux.Handle(unary(name))
+	}
+
+	mux.Handle(procedure("Watch"), connect.NewServerStreamHandler(procedure("Watch"),
+		func(_ context.Context, _ *connect.Request[wrapperspb.StringValue], stream *connect.ServerStream[emptypb.Empty]) error {
+			return stream.Send(&emptypb.Empty{})
+		},
+		connect.WithSchema(methods.ByName("Watch")), interceptor))
+
+	// A handler created without a schema cannot be checked.
+	mux.Handle("/test.v1.ArticleService/Unknown", connect.NewUnaryHandler("/test.v1.ArticleService/Unknown",
+		func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}, interceptor))
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	call := func(name, token, unit string) error {
+		req := connect.NewRequest(wrapperspb.String(unit))
+		if token != "" {
+			req.Header().Set("Authorization", "Bearer "+token)
+		}
+
+		client := connect.NewClient[wrapperspb.StringValue, emptypb.Empty](server.Client(),
+			server.URL+"/test.v1.ArticleService/"+name)
+
+		if name != "Watch" {
+			_, err := client.CallUnary(context.Background(), req)
+
+			return err //nolint:wrapcheck // test helper
+		}
+
+		stream, err := client.CallServerStream(context.Background(), req)
+		if err != nil {
+			return err //nolint:wrapcheck // test helper
+		}
+
+		defer func() { _ = stream.Close() }()
+
+		for stream.Receive() {
+		}
+
+		return stream.Err() //nolint:wrapcheck // test helper
+	}
+
+	tests := []struct {
+		method string
+		token  string
+		unit   string
+		code   connect.Code
+	}{
+		{method: "Ping"},
+		{method: "List", code: connect.CodeUnauthenticated},
+		{method: "List", token: "nobody", code: connect.CodePermissionDenied},
+		{method: "List", token: "unit-reader", code: connect.CodePermissionDenied},
+		{method: "List", token: "org-reader"},
+		{method: "Get", token: "unit-reader", unit: "unit-a"},
+		{method: "Get", token: "unit-reader", unit: "unit-b", code: connect.CodePermissionDenied},
+		{method: "Get", token: "org-reader", unit: "unit-b"},
+		{method: "Get", token: "unit-reader", code: connect.CodeInvalidArgument},
+		{method: "Watch", token: "unit-reader", unit: "unit-a"},
+		{method: "Watch", token: "unit-reader", unit: "unit-b", code: connect.CodePermissionDenied},
+		{method: "Delete", token: "org-reader", code: connect.CodePermissionDenied},
+		{method: "Count", token: "org-reader", code: connect.CodePermissionDenied},
+		{method: "Stats", code: connect.CodeUnauthenticated},
+		{method: "Stats", token: "nobody"},
+		{method: "Export", code: connect.CodePermissionDenied},
+		{method: "Export", token: "org-reader", code: connect.CodePermissionDenied},
+		{method: "Unknown", token: "org-reader", code: connect.CodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+"/"+tt.token+"/"+tt.unit, func(t *testing.T) {
+			err := call(tt.method, tt.token, tt.unit)
+			if tt.code == 0 && err != nil {
+				t.Fatalf("Expected success, got %v", err)
+			}
+
+			if tt.code != 0 && connect.CodeOf(err) != tt.code {
+				t.Errorf("Expected %v, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestWithAuthRuleValidation(t *testing.T) {
+	service := articleService(t, "test.validated.v1")
+
+	if err := protoregistry.GlobalFiles.RegisterFile(service.ParentFile()); err != nil {
+		t.Fatalf("Failed to register descriptor: %v", err)
+	}
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithAuthRuleValidation(),
+		dindenault.WithPlainService("/test.validated.v1.ArticleService/", http.NotFoundHandler()),
+	)
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil || !strings.Contains(recovered.(string), "test.validated.v1.ArticleService.Delete") {
+			t.Errorf("Expected a panic listing the unannotated method, got %v", recovered)
+		}
+	}()
+
+	app.HTTPHandler()
+}
@@ -114,6 +114,15 @@ type ConnectHandlerWithInterceptor interface {
 // WithInterceptors. Use this for non-Connect handlers (health checks,
 // webhooks, and similar) registered on an App that uses WithInterceptors.
 //
+// The path is a pattern as described for WithService, so REST-style
+// handlers can use method prefixes and path parameters:
+//
+//	dindenault.WithPlainService("GET /articles/{id}", http.HandlerFunc(
+//	    func(w http.ResponseWriter, r *http.Request) {
+//	        article := lookup(r.PathValue("id"))
+//	        ...
+//	    }))
+//
 // Note that opting out also opts out of any authentication configured
 // via app-level interceptors — the handler is responsible for its own
 // access control.
@@ -135,6 +144,23 @@ func WithPlainService(path string, handler http.Handler) Option {
 // configured with WithInterceptors will be automatically applied if the
 // handler supports them.
 //
+// The path is an http.ServeMux pattern, with one addition for
+// compatibility with earlier versions:
+//
+//   - "/pkg.v1.Service/" (trailing slash) matches the path and
+//     everything below it. Connect service handlers use this form.
+//   - "GET /items/{id}" matches the method and path exactly; wildcard
+//     values are available through http.Request.PathValue. A trailing
+//     "{rest...}" wildcard matches the remainder of the path.
+//   - "/webhook" (no method, no wildcards, no trailing slash) matches
+//     the path itself and everything below it, on segment boundaries:
+//     "/webhook/github" matches but "/webhooks" does not.
+//
+// Matching is case-sensitive, and when several registrations match a
+// request the most specific one wins. Registrations that conflict —
+// two patterns matching the same requests with neither being more
+// specific — make the App panic at startup.
+//
 // Example - Simple service registration:
 //
 //	path, handler := servicev1connect.NewServiceHandler(impl)
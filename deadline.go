@@ -0,0 +1,94 @@
+package dindenault
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/navigacontentlab/dindenault/internal/lambda"
+)
+
+// deadlineExceededMessage is returned to clients whose request did not
+// finish before the Lambda deadline.
+const deadlineExceededMessage = "request did not complete before the Lambda deadline"
+
+// WithDeadlineMargin makes request contexts expire margin before the
+// Lambda invocation deadline, so that handlers — and the downstream
+// calls they make — are cancelled while there is still time to answer.
+//
+// If a handler does not return by then, the App stops waiting for it
+// and returns a Connect deadline_exceeded error (or an HTTP 504 for
+// plain requests) instead of letting the runtime kill the invocation
+// and leave the client with a generic gateway error. The margin should
+// cover the time needed to return the response; one to two seconds is
+// usually enough.
+//
+// A Connect-Timeout-Ms (or grpc-timeout) sent by the client still
+// applies: Connect derives the handler context from the request
+// context, so whichever deadline is earlier wins.
+//
+// The margin is disabled by default. It only applies to the Lambda
+// entry points, not to HTTPHandler.
+func WithDeadlineMargin(margin time.Duration) Option {
+	return func(a *App) {
+		a.deadlineMargin = margin
+	}
+}
+
+// withDeadline derives the context for a Lambda request, expiring the
+// configured margin before the invocation deadline.
+func (a *App) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if a.deadlineMargin <= 0 || !ok {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithDeadline(ctx, deadline.Add(-a.deadlineMargin))
+}
+
+// serveBuffered dispatches req to a buffered response writer. When a
+// deadline margin is configured and ctx expires before the handler
+// returns, the handler is abandoned and a deadline_exceeded response is
+// returned in its place.
+func (a *App) serveBuffered(ctx context.Context, req *http.Request) *lambda.ProxyResponseWriter {
+	w := lambda.NewProxyResponseWriter(a.binaryMediaTypes...)
+
+	if a.deadlineMargin <= 0 {
+		a.dispatch(w, req)
+
+		return w
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		a.dispatch(w, req)
+	}()
+
+	select {
+	case <-done:
+		return w
+	case <-ctx.Done():
+	}
+
+	// Prefer the handler's own response if it finished in the meantime,
+	// for example with a deadline_exceeded error of its own.
+	select {
+	case <-done:
+		return w
+	default:
+	}
+
+	a.logger.WarnContext(ctx, "Handler did not return before the Lambda deadline",
+		"path", req.URL.Path,
+		"margin", a.deadlineMargin)
+
+	tw := lambda.NewProxyResponseWriter(a.binaryMediaTypes...)
+	writeError(tw, req, connect.CodeDeadlineExceeded, http.StatusGatewayTimeout, deadlineExceededMessage)
+
+	return tw
+}
@@ -0,0 +1,269 @@
+package dindenault
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+const (
+	// healthCheckTimeout bounds each readiness check, so that a hanging
+	// dependency makes the App unready instead of hanging the probe.
+	healthCheckTimeout = 5 * time.Second
+
+	healthStatusOK   = "ok"
+	healthStatusFail = "fail"
+)
+
+// HealthCheck is a named readiness check. Check should return an error
+// when a dependency the App needs to serve requests is unavailable. It
+// is called with a context that is cancelled after five seconds.
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// JWKSHealthCheck returns a HealthCheck that verifies that the JWKS
+// used for token validation holds usable keys. The keys are only
+// fetched when the cached ones are stale, so frequent probes do not
+// hit the JWKS endpoint.
+func JWKSHealthCheck(jwks *navigaid.JWKS) HealthCheck {
+	return HealthCheck{
+		Name:  "jwks",
+		Check: jwks.Ready,
+	}
+}
+
+// HTTPHealthCheck returns a HealthCheck that sends a GET request to url
+// and fails unless it gets a 2xx or 3xx response.
+func HTTPHealthCheck(name, url string) HealthCheck {
+	return HealthCheck{
+		Name: name,
+		Check: func(ctx context.Context) error {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return fmt.Errorf("failed to create request: %w", err)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				return fmt.Errorf("request failed: %w", err)
+			}
+
+			_ = res.Body.Close()
+
+			if res.StatusCode >= http.StatusBadRequest {
+				return fmt.Errorf("server responded with: %s", res.Status)
+			}
+
+			return nil
+		},
+	}
+}
+
+// WithHealth serves health endpoints below path, without app-level
+// interceptors (so probes need no credentials):
+//
+//   - GET {path}/live answers 200 as long as the App is running.
+//   - GET {path}/ready and GET {path} run the checks concurrently and
+//     answer 200, or 503 if any of them fails.
+//   - GET {path}/version returns the module version and VCS revision
+//     from runtime/debug.ReadBuildInfo.
+//
+// It also serves the standard grpc.health.v1.Health service, so gRPC
+// tooling and load balancers can use Check. An empty service name
+// reports on the App as a whole, the names of registered Connect
+// services report the same readiness, and other names are NOT_FOUND.
+// Watch is not supported.
+//
+// Check failures are logged; responses only name the failing checks.
+//
+// Example:
+//
+//	app := dindenault.New(logger,
+//	    dindenault.WithHealth("/health",
+//	        dindenault.JWKSHealthCheck(jwks),
+//	        dindenault.HTTPHealthCheck("opencontent", ocURL+"/health"),
+//	    ),
+//	)
+func WithHealth(path string, checks ...HealthCheck) Option {
+	return func(a *App) {
+		health := &healthHandler{
+			app:    a,
+			path:   strings.TrimSuffix(path, "/"),
+			checks: checks,
+		}
+
+		grpcPath, grpcHandler := grpchealth.NewHandler(health)
+
+		a.registrations = append(a.registrations,
+			Registration{
+				Path:                   path,
+				Handler:                http.HandlerFunc(health.serveHTTP),
+				SkipGlobalInterceptors: true,
+			},
+			Registration{
+				Path:                   grpcPath,
+				Handler:                grpcHandler,
+				SkipGlobalInterceptors: true,
+			},
+		)
+	}
+}
+
+// healthHandler serves the endpoints configured with WithHealth.
+type healthHandler struct {
+	app    *App
+	path   string
+	checks []HealthCheck
+}
+
+// healthReport is the body of the readiness endpoint.
+type healthReport struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (h *healthHandler) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeProblem(w, http.StatusMethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch strings.TrimPrefix(r.URL.Path, h.path) {
+	case "", "/", "/ready":
+		report := h.ready(r.Context())
+
+		status := http.StatusOK
+		if report.Status != healthStatusOK {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, report)
+	case "/live":
+		writeJSON(w, http.StatusOK, healthReport{Status: healthStatusOK})
+	case "/version":
+		writeJSON(w, http.StatusOK, buildVersion())
+	default:
+		writeProblem(w, http.StatusNotFound, "No health endpoint at "+r.URL.Path)
+	}
+}
+
+// ready runs the checks concurrently and reports their outcome.
+func (h *healthHandler) ready(ctx context.Context) healthReport {
+	report := healthReport{
+		Status: healthStatusOK,
+		Checks: make(map[string]string, len(h.checks)),
+	}
+
+	var (
+		m  sync.Mutex
+		wg sync.WaitGroup
+	)
+
+	for _, check := range h.checks {
+		wg.Go(func() {
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+
+			status := healthStatusOK
+
+			if err := check.Check(ctx); err != nil {
+				h.app.logger.WarnContext(ctx, "Health check failed",
+					"check", check.Name,
+					"error", err)
+
+				status = healthStatusFail
+			}
+
+			m.Lock()
+			defer m.Unlock()
+
+			report.Checks[check.Name] = status
+			if status != healthStatusOK {
+				report.Status = healthStatusFail
+			}
+		})
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+// Check implements grpchealth.Checker for the grpc.health.v1.Health
+// service.
+func (h *healthHandler) Check(
+	ctx context.Context, req *grpchealth.CheckRequest,
+) (*grpchealth.CheckResponse, error) {
+	if service := req.Service; service != "" && !slices.Contains(h.app.ServiceNames(), service) {
+		return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("unknown service %q", service))
+	}
+
+	status := grpchealth.StatusServing
+	if h.ready(ctx).Status != healthStatusOK {
+		status = grpchealth.StatusNotServing
+	}
+
+	return &grpchealth.CheckResponse{Status: status}, nil
+}
+
+// versionInfo is the body of the version endpoint.
+type versionInfo struct {
+	Path        string `json:"path,omitempty"`
+	Version     string `json:"version,omitempty"`
+	GoVersion   string `json:"go_version,omitempty"`
+	VCSRevision string `json:"vcs_revision,omitempty"`
+	VCSTime     string `json:"vcs_time,omitempty"`
+	VCSModified bool   `json:"vcs_modified,omitempty"`
+}
+
+// buildVersion reads the version information embedded in the binary.
+var buildVersion = sync.OnceValue(func() versionInfo {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versionInfo{}
+	}
+
+	version := versionInfo{
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+		GoVersion: info.GoVersion,
+	}
+
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			version.VCSRevision = setting.Value
+		case "vcs.time":
+			version.VCSTime = setting.Value
+		case "vcs.modified":
+			version.VCSModified = setting.Value == "true"
+		}
+	}
+
+	return version
+})
+
+// writeJSON writes v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,144 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+func TestWithHealth(t *testing.T) {
+	var dependencyErr error
+
+	jwksServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"keys":[{"kty":"OKP","alg":"EdDSA","crv":"Ed25519","kid":"key-1","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`))
+	}))
+	defer jwksServer.Close()
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithInterceptors(dindenault.LoggingInterceptors(slog.Default())),
+		dindenault.WithService("/pkg.v1.Service/", &mockConnectHandler{}),
+		dindenault.WithHealth("/health",
+			dindenault.JWKSHealthCheck(navigaid.NewJWKS(jwksServer.URL)),
+			dindenault.HTTPHealthCheck("upstream", jwksServer.URL),
+			dindenault.HealthCheck{
+				Name:  "dependency",
+				Check: func(context.Context) error { return dependencyErr },
+			},
+		),
+	)
+
+	handler := app.HTTPHandler()
+
+	get := func(t *testing.T, path string) (int, map[string]any) {
+		t.Helper()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid JSON body %q: %v", rec.Body.String(), err)
+		}
+
+		return rec.Code, body
+	}
+
+	checkGRPC := func(t *testing.T, service string) *httptest.ResponseRecorder {
+		t.Helper()
+
+		req := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check",
+			strings.NewReader(`{"service":"`+service+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("liveness", func(t *testing.T) {
+		status, body := get(t, "/health/live")
+		if status != http.StatusOK || body["status"] != "ok" {
+			t.Errorf("Expected live, got %d %v", status, body)
+		}
+	})
+
+	t.Run("readiness with passing checks", func(t *testing.T) {
+		for _, path := range []string{"/health", "/health/ready"} {
+			status, body := get(t, path)
+			if status != http.StatusOK {
+				t.Errorf("%s: expected status 200, got %d %v", path, status, body)
+			}
+
+			checks, _ := body["checks"].(map[string]any)
+			if checks["jwks"] != "ok" || checks["upstream"] != "ok" || checks["dependency"] != "ok" {
+				t.Errorf("%s: unexpected checks %v", path, checks)
+			}
+		}
+	})
+
+	t.Run("readiness with failing check", func(t *testing.T) {
+		dependencyErr = errors.New("database password is hunter2")
+		defer func() { dependencyErr = nil }()
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %d", rec.Code)
+		}
+
+		if strings.Contains(rec.Body.String(), "hunter2") {
+			t.Error("Check errors must not be returned to clients")
+		}
+
+		if rec := checkGRPC(t, ""); !strings.Contains(rec.Body.String(), `"SERVING_STATUS_NOT_SERVING"`) {
+			t.Errorf("Expected SERVING_STATUS_NOT_SERVING, got %q", rec.Body.String())
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		status, body := get(t, "/health/version")
+		if status != http.StatusOK || body["go_version"] == nil {
+			t.Errorf("Expected build info, got %d %v", status, body)
+		}
+	})
+
+	t.Run("grpc health check", func(t *testing.T) {
+		for _, service := range []string{"", "pkg.v1.Service"} {
+			rec := checkGRPC(t, service)
+			if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"SERVING_STATUS_SERVING"`) {
+				t.Errorf("%q: expected SERVING_STATUS_SERVING, got %d %q", service, rec.Code, rec.Body.String())
+			}
+		}
+	})
+
+	t.Run("grpc health check for unknown service", func(t *testing.T) {
+		rec := checkGRPC(t, "pkg.v1.Unknown")
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), "not_found") {
+			t.Errorf("Expected not_found, got %d %q", rec.Code, rec.Body.String())
+		}
+	})
+
+	t.Run("grpc health check in binary encoding", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/grpc.health.v1.Health/Check", http.NoBody)
+		req.Header.Set("Content-Type", "application/proto")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		// Field 1 (varint) = SERVING (1).
+		if !bytes.Equal(rec.Body.Bytes(), []byte{0x08, 0x01}) {
+			t.Errorf("Expected SERVING response, got %x", rec.Body.Bytes())
+		}
+	})
+}
@@ -1,29 +1,54 @@
 // Package httpforward provides a shared http.RoundTripper that injects a
-// fixed Authorization header on every outbound request. It is the transport
-// backing both mcp.NewHTTPClient and navigaid.NewHTTPClient.
+// fixed Authorization header and the caller's request ID on every
+// outbound request. It is the transport backing both mcp.NewHTTPClient
+// and navigaid.NewHTTPClient.
 package httpforward
 
-import "net/http"
+import (
+	"context"
+	"net/http"
+
+	"github.com/navigacontentlab/dindenault/internal/requestid"
+)
 
 // NewTransport returns an http.RoundTripper that sets the Authorization header
 // to token on every request, cloning the request first so the original is
 // never mutated. If base is nil, http.DefaultTransport is used.
-func NewTransport(token string, base http.RoundTripper) http.RoundTripper {
+//
+// The request ID carried by ctx is sent in the X-Request-Id header, unless
+// the outbound request sets one or its own context carries a request ID.
+func NewTransport(ctx context.Context, token string, base http.RoundTripper) http.RoundTripper {
 	if base == nil {
 		base = http.DefaultTransport
 	}
 
-	return &forwardingTransport{base: base, token: token}
+	return &forwardingTransport{
+		base:      base,
+		token:     token,
+		requestID: requestid.FromContext(ctx),
+	}
 }
 
 type forwardingTransport struct {
-	base  http.RoundTripper
-	token string
+	base      http.RoundTripper
+	token     string
+	requestID string
 }
 
 func (t *forwardingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	r = r.Clone(r.Context())
 	r.Header.Set("Authorization", t.token)
 
+	if r.Header.Get(requestid.Header) == "" {
+		requestID := requestid.FromContext(r.Context())
+		if requestID == "" {
+			requestID = t.requestID
+		}
+
+		if requestID != "" {
+			r.Header.Set(requestid.Header, requestID)
+		}
+	}
+
 	return t.base.RoundTrip(r) //nolint:wrapcheck // RoundTrip errors must not be wrapped; callers inspect the concrete type (e.g. *url.Error)
 }
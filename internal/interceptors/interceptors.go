@@ -3,11 +3,17 @@ package interceptors
 
 import (
 	"context"
+	"errors"
+	"io"
 	"log/slog"
+	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
+
+	"github.com/navigacontentlab/dindenault/internal/requestid"
 )
 
 // ExtractServiceAndMethod extracts the service name and method name from a Connect RPC procedure path.
@@ -35,54 +41,199 @@ func ExtractServiceAndMethod(procedure string) (string, string) {
 	return service, method
 }
 
-// Logging creates a Connect interceptor that logs requests with timing information.
+// Logging creates a Connect interceptor that logs requests with timing
+// information. Streaming calls are logged when the stream starts and
+// when it ends.
 //
 //nolint:ireturn
 func Logging(logger *slog.Logger) connect.Interceptor {
 	logger.Debug("Creating logging interceptor")
 
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Extract procedure information
-			procedure := req.Spec().Procedure
-			service, method := ExtractServiceAndMethod(procedure)
+	return &loggingInterceptor{logger: logger}
+}
 
-			// Store start time
-			start := time.Now()
+type loggingInterceptor struct {
+	logger *slog.Logger
+}
 
-			// Create log attributes
-			logAttrs := []any{
-				"service", service,
-				"method", method,
-				"procedure", procedure,
-			}
+func (i *loggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		finish := i.start(ctx, req.Spec(), req.Header())
 
-			// Extract request ID if present in headers
-			if requestID := req.Header().Get("X-Request-ID"); requestID != "" {
-				logAttrs = append(logAttrs, "request_id", requestID)
-			}
+		// Process the request
+		resp, err := next(ctx, req)
 
-			// Log request start
-			logger.Info("Connect RPC request started", logAttrs...)
+		finish(err)
 
-			// Process the request
-			resp, err := next(ctx, req)
+		return resp, err
+	}
+}
+
+func (i *loggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+
+		return NewStreamingClientConn(conn, i.start(ctx, spec, conn.RequestHeader()))
+	}
+}
 
-			// Calculate duration
-			duration := time.Since(start)
+func (i *loggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		finish := i.start(ctx, conn.Spec(), conn.RequestHeader())
 
-			// Add duration to log attributes
-			logAttrs = append(logAttrs, "duration_ms", duration.Milliseconds())
+		// Process the stream
+		err := next(ctx, conn)
 
-			// Add error information if present
-			if err != nil {
-				logAttrs = append(logAttrs, "error", err.Error())
-				logger.Error("Connect RPC request failed", logAttrs...)
-			} else {
-				logger.Info("Connect RPC request completed", logAttrs...)
-			}
+		finish(err)
 
-			return resp, err
+		return err
+	}
+}
+
+// start logs the start of a call and returns the function that logs
+// its outcome.
+func (i *loggingInterceptor) start(ctx context.Context, spec connect.Spec, header http.Header) func(error) {
+	// Extract procedure information
+	procedure := spec.Procedure
+	service, method := ExtractServiceAndMethod(procedure)
+
+	// Store start time
+	start := time.Now()
+
+	// Create log attributes
+	logAttrs := []any{
+		"service", service,
+		"method", method,
+		"procedure", procedure,
+	}
+
+	if spec.StreamType != connect.StreamTypeUnary {
+		logAttrs = append(logAttrs, "stream_type", spec.StreamType.String())
+	}
+
+	// Add the request ID assigned by the App, or the one sent
+	// by the client when used outside an App
+	requestID := requestid.FromContext(ctx)
+	if requestID == "" {
+		requestID = header.Get(requestid.Header)
+	}
+
+	if requestID != "" {
+		logAttrs = append(logAttrs, "request_id", requestID)
+	}
+
+	// Log request start
+	i.logger.InfoContext(ctx, "Connect RPC request started", logAttrs...)
+
+	return func(err error) {
+		// Add duration to log attributes
+		logAttrs = append(logAttrs, "duration_ms", time.Since(start).Milliseconds())
+
+		// Add error information if present
+		if err != nil {
+			logAttrs = append(logAttrs, "error", err.Error())
+			i.logger.ErrorContext(ctx, "Connect RPC request failed", logAttrs...)
+		} else {
+			i.logger.InfoContext(ctx, "Connect RPC request completed", logAttrs...)
+		}
+	}
+}
+
+// HandlerCheck inspects an incoming call before it reaches the handler.
+// It returns the context to continue with, or an error that fails the
+// call.
+type HandlerCheck func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error)
+
+// Handler returns an interceptor that runs check before unary calls and
+// before streaming handlers, so that streaming procedures cannot bypass
+// it. Outgoing streams of clients are passed through unchecked.
+//
+//nolint:ireturn
+func Handler(check HandlerCheck) connect.Interceptor {
+	return handlerInterceptor(check)
+}
+
+type handlerInterceptor HandlerCheck
+
+func (check handlerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, err := check(ctx, req.Spec(), req.Header())
+		if err != nil {
+			return nil, err
 		}
+
+		return next(ctx, req)
+	}
+}
+
+func (check handlerInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (check handlerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := check(ctx, conn.Spec(), conn.RequestHeader())
+		if err != nil {
+			return err
+		}
+
+		return next(ctx, conn)
+	}
+}
+
+// NewStreamingClientConn wraps conn so that finish is called once with
+// the outcome of the stream when the response is closed. The outcome is
+// the first error other than io.EOF returned by Send, Receive or
+// CloseResponse.
+//
+//nolint:ireturn
+func NewStreamingClientConn(conn connect.StreamingClientConn, finish func(error)) connect.StreamingClientConn {
+	return &streamingClientConn{StreamingClientConn: conn, finish: finish}
+}
+
+type streamingClientConn struct {
+	connect.StreamingClientConn
+
+	finish func(error)
+	once   sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *streamingClientConn) Send(msg any) error {
+	return c.record(c.StreamingClientConn.Send(msg))
+}
+
+func (c *streamingClientConn) Receive(msg any) error {
+	return c.record(c.StreamingClientConn.Receive(msg))
+}
+
+func (c *streamingClientConn) CloseResponse() error {
+	err := c.record(c.StreamingClientConn.CloseResponse())
+
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.finish(c.err)
 	})
+
+	return err
+}
+
+// record remembers the first error of the stream and returns err.
+func (c *streamingClientConn) record(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+
+	return err
 }
@@ -0,0 +1,83 @@
+package lambda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// EventType identifies the trigger that produced a Lambda HTTP event.
+type EventType int
+
+// Known HTTP event types.
+const (
+	EventTypeUnknown EventType = iota
+	EventTypeALB
+	EventTypeAPIGatewayV1
+	EventTypeAPIGatewayV2
+	EventTypeFunctionURL
+)
+
+// String returns a human-readable name for the event type.
+func (t EventType) String() string {
+	switch t {
+	case EventTypeALB:
+		return "alb"
+	case EventTypeAPIGatewayV1:
+		return "apigateway-v1"
+	case EventTypeAPIGatewayV2:
+		return "apigateway-v2"
+	case EventTypeFunctionURL:
+		return "function-url"
+	case EventTypeUnknown:
+	}
+
+	return "unknown"
+}
+
+// ErrUnknownEvent is returned by DetectEventType for payloads that are
+// not recognised as HTTP events.
+var ErrUnknownEvent = errors.New("unrecognised lambda event")
+
+// DetectEventType works out which trigger produced a raw Lambda event
+// payload by inspecting the fields that distinguish the formats:
+//
+//   - requestContext.elb is only present in ALB events
+//   - version "2.0" is used by API Gateway v2 and Function URLs, which
+//     are told apart by the ".lambda-url." Function URL domain name
+//   - httpMethod together with a requestContext identifies API Gateway
+//     REST API (v1) proxy events
+func DetectEventType(payload []byte) (EventType, error) {
+	var peek struct {
+		Version        string `json:"version"`
+		HTTPMethod     string `json:"httpMethod"`
+		RequestContext *struct {
+			ELB        json.RawMessage `json:"elb"`
+			DomainName string          `json:"domainName"`
+			Stage      string          `json:"stage"`
+			RequestID  string          `json:"requestId"`
+		} `json:"requestContext"`
+	}
+
+	if err := json.Unmarshal(payload, &peek); err != nil {
+		return EventTypeUnknown, fmt.Errorf("failed to decode lambda event: %w", err)
+	}
+
+	rc := peek.RequestContext
+
+	switch {
+	case rc == nil:
+		return EventTypeUnknown, ErrUnknownEvent
+	case len(rc.ELB) > 0:
+		return EventTypeALB, nil
+	case peek.Version == apiGatewayV2 && strings.Contains(rc.DomainName, ".lambda-url."):
+		return EventTypeFunctionURL, nil
+	case peek.Version == apiGatewayV2:
+		return EventTypeAPIGatewayV2, nil
+	case peek.HTTPMethod != "" && (rc.Stage != "" || rc.RequestID != ""):
+		return EventTypeAPIGatewayV1, nil
+	}
+
+	return EventTypeUnknown, ErrUnknownEvent
+}
@@ -13,7 +13,10 @@ import (
 	awsevents "github.com/aws/aws-lambda-go/events"
 )
 
-const apiGatewayV2 = "2.0"
+const (
+	payloadV1    = "1.0"
+	apiGatewayV2 = "2.0"
+)
 
 // RequestContext combines the relevant fields from ALB and API Gateway contexts.
 type RequestContext struct {
@@ -31,6 +34,14 @@ type RequestContext struct {
 	ELB struct {
 		TargetGroupArn string `json:"targetGroupArn"`
 	} `json:"elb"`
+
+	// API Gateway and Function URL fields
+	RequestID  string         `json:"requestId"`
+	Stage      string         `json:"stage"`
+	APIID      string         `json:"apiId"`
+	AccountID  string         `json:"accountId"`
+	DomainName string         `json:"domainName"`
+	Authorizer map[string]any `json:"authorizer"`
 }
 
 // Request is a generic request that works with both ALB and API Gateway.
@@ -47,12 +58,15 @@ type Request struct {
 	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
 	RawPath                         string              `json:"rawPath"`
 	RawQueryString                  string              `json:"rawQueryString"`
+
+	// EventType records which trigger produced the request.
+	EventType EventType `json:"-"`
 }
 
 // FromALBRequest converts an ALB request to a generic Request.
 func FromALBRequest(alb awsevents.ALBTargetGroupRequest) Request {
 	req := Request{
-		Version:                         "1.0",
+		Version:                         payloadV1,
 		Path:                            alb.Path,
 		HTTPMethod:                      alb.HTTPMethod,
 		Headers:                         alb.Headers,
@@ -61,6 +75,7 @@ func FromALBRequest(alb awsevents.ALBTargetGroupRequest) Request {
 		MultiValueQueryStringParameters: alb.MultiValueQueryStringParameters,
 		Body:                            alb.Body,
 		IsBase64Encoded:                 alb.IsBase64Encoded,
+		EventType:                       EventTypeALB,
 	}
 
 	req.RequestContext.ELB.TargetGroupArn = alb.RequestContext.ELB.TargetGroupArn
@@ -74,11 +89,12 @@ func FromAPIGatewayRequest(apigw awsevents.APIGatewayV2HTTPRequest) Request {
 		Version:         apiGatewayV2,
 		Path:            apigw.RawPath,
 		HTTPMethod:      apigw.RequestContext.HTTP.Method,
-		Headers:         apigw.Headers,
+		Headers:         withCookieHeader(apigw.Headers, apigw.Cookies),
 		RawPath:         apigw.RawPath,
 		RawQueryString:  apigw.RawQueryString,
 		Body:            apigw.Body,
 		IsBase64Encoded: apigw.IsBase64Encoded,
+		EventType:       EventTypeAPIGatewayV2,
 	}
 
 	// Manually copy HTTP fields
@@ -88,9 +104,222 @@ func FromAPIGatewayRequest(apigw awsevents.APIGatewayV2HTTPRequest) Request {
 	req.RequestContext.HTTP.SourceIP = apigw.RequestContext.HTTP.SourceIP
 	req.RequestContext.HTTP.UserAgent = apigw.RequestContext.HTTP.UserAgent
 
+	req.RequestContext.RequestID = apigw.RequestContext.RequestID
+	req.RequestContext.Stage = apigw.RequestContext.Stage
+	req.RequestContext.APIID = apigw.RequestContext.APIID
+	req.RequestContext.AccountID = apigw.RequestContext.AccountID
+	req.RequestContext.DomainName = apigw.RequestContext.DomainName
+	req.RequestContext.Authorizer = apiGatewayV2Authorizer(apigw.RequestContext.Authorizer)
+
+	return req
+}
+
+// FromFunctionURLRequest converts a Lambda Function URL request to a
+// generic Request. Function URLs use the API Gateway v2 payload format.
+func FromFunctionURLRequest(furl awsevents.LambdaFunctionURLRequest) Request {
+	req := Request{
+		Version:         apiGatewayV2,
+		Path:            furl.RawPath,
+		HTTPMethod:      furl.RequestContext.HTTP.Method,
+		Headers:         withCookieHeader(furl.Headers, furl.Cookies),
+		RawPath:         furl.RawPath,
+		RawQueryString:  furl.RawQueryString,
+		Body:            furl.Body,
+		IsBase64Encoded: furl.IsBase64Encoded,
+		EventType:       EventTypeFunctionURL,
+	}
+
+	req.RequestContext.HTTP.Method = furl.RequestContext.HTTP.Method
+	req.RequestContext.HTTP.Path = furl.RequestContext.HTTP.Path
+	req.RequestContext.HTTP.Protocol = furl.RequestContext.HTTP.Protocol
+	req.RequestContext.HTTP.SourceIP = furl.RequestContext.HTTP.SourceIP
+	req.RequestContext.HTTP.UserAgent = furl.RequestContext.HTTP.UserAgent
+
+	req.RequestContext.RequestID = furl.RequestContext.RequestID
+	req.RequestContext.APIID = furl.RequestContext.APIID
+	req.RequestContext.AccountID = furl.RequestContext.AccountID
+	req.RequestContext.DomainName = furl.RequestContext.DomainName
+
+	if iam := furl.RequestContext.Authorizer; iam != nil && iam.IAM != nil {
+		req.RequestContext.Authorizer = map[string]any{"iam": iam.IAM}
+	}
+
 	return req
 }
 
+// FromAPIGatewayV1Request converts an API Gateway REST API (v1) proxy
+// request to a generic Request.
+//
+// API Gateway v1 delivers every header and query parameter in both the
+// single- and the multi-value maps. Only the multi-value maps are kept
+// when present, so repeated values are not duplicated when the request
+// is converted with AWSRequestToHTTPRequest.
+func FromAPIGatewayV1Request(apigw awsevents.APIGatewayProxyRequest) Request {
+	req := Request{
+		Version:         payloadV1,
+		Path:            stripStage(apigw),
+		HTTPMethod:      apigw.HTTPMethod,
+		Body:            apigw.Body,
+		IsBase64Encoded: apigw.IsBase64Encoded,
+		EventType:       EventTypeAPIGatewayV1,
+	}
+
+	if len(apigw.MultiValueHeaders) > 0 {
+		req.MultiValueHeaders = apigw.MultiValueHeaders
+	} else {
+		req.Headers = apigw.Headers
+	}
+
+	if len(apigw.MultiValueQueryStringParameters) > 0 {
+		req.MultiValueQueryStringParameters = apigw.MultiValueQueryStringParameters
+	} else {
+		req.QueryStringParameters = apigw.QueryStringParameters
+	}
+
+	req.RequestContext.HTTP.Method = apigw.RequestContext.HTTPMethod
+	req.RequestContext.HTTP.Path = apigw.RequestContext.Path
+	req.RequestContext.HTTP.Protocol = apigw.RequestContext.Protocol
+	req.RequestContext.HTTP.SourceIP = apigw.RequestContext.Identity.SourceIP
+	req.RequestContext.HTTP.UserAgent = apigw.RequestContext.Identity.UserAgent
+
+	req.RequestContext.RequestID = apigw.RequestContext.RequestID
+	req.RequestContext.Stage = apigw.RequestContext.Stage
+	req.RequestContext.APIID = apigw.RequestContext.APIID
+	req.RequestContext.AccountID = apigw.RequestContext.AccountID
+	req.RequestContext.DomainName = apigw.RequestContext.DomainName
+	req.RequestContext.Authorizer = apigw.RequestContext.Authorizer
+
+	return req
+}
+
+// withCookieHeader folds the cookies of a v2 payload, which API Gateway
+// and Function URLs deliver separately from the headers, back into a
+// Cookie header. The original header map is not modified.
+func withCookieHeader(headers map[string]string, cookies []string) map[string]string {
+	if len(cookies) == 0 {
+		return headers
+	}
+
+	merged := make(map[string]string, len(headers)+1)
+	cookie := strings.Join(cookies, "; ")
+
+	for k, v := range headers {
+		if strings.EqualFold(k, "cookie") {
+			cookie = v + "; " + cookie
+
+			continue
+		}
+
+		merged[k] = v
+	}
+
+	merged["cookie"] = cookie
+
+	return merged
+}
+
+// apiGatewayV2Authorizer flattens the typed API Gateway v2 authorizer
+// description into the map form used by REST APIs, keyed by authorizer
+// type ("jwt", "lambda" or "iam").
+func apiGatewayV2Authorizer(a *awsevents.APIGatewayV2HTTPRequestContextAuthorizerDescription) map[string]any {
+	if a == nil {
+		return nil
+	}
+
+	authorizer := make(map[string]any)
+
+	if a.JWT != nil {
+		authorizer["jwt"] = map[string]any{
+			"claims": a.JWT.Claims,
+			"scopes": a.JWT.Scopes,
+		}
+	}
+
+	if a.Lambda != nil {
+		authorizer["lambda"] = a.Lambda
+	}
+
+	if a.IAM != nil {
+		authorizer["iam"] = a.IAM
+	}
+
+	return authorizer
+}
+
+// SourceIP returns the client IP address of the request. API Gateway and
+// Function URLs report it in the request context; for ALB events it is
+// the last address in the X-Forwarded-For header, which is the one the
+// load balancer appended — earlier entries are supplied by the client
+// and cannot be trusted.
+func (r Request) SourceIP() string {
+	if r.RequestContext.HTTP.SourceIP != "" {
+		return r.RequestContext.HTTP.SourceIP
+	}
+
+	forwarded := r.header("X-Forwarded-For")
+	if forwarded == "" {
+		return ""
+	}
+
+	addrs := strings.Split(forwarded, ",")
+
+	return strings.TrimSpace(addrs[len(addrs)-1])
+}
+
+// TraceID returns the X-Amzn-Trace-Id header added by ALB and
+// API Gateway, or an empty string.
+func (r Request) TraceID() string {
+	return r.header("X-Amzn-Trace-Id")
+}
+
+// header returns the first value of the named header from either
+// header map. Lambda events do not normalise header name casing.
+func (r Request) header(name string) string {
+	for k, v := range r.Headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+
+	for k, vals := range r.MultiValueHeaders {
+		if strings.EqualFold(k, name) && len(vals) > 0 {
+			return vals[0]
+		}
+	}
+
+	return ""
+}
+
+// stripStage removes a leading "/{stage}" segment from the event path.
+// API Gateway normally delivers path without the stage, so the segment
+// is only stripped when the event shows that it is present: path equals
+// the request context path (which always carries the stage) and the
+// matched resource does not itself begin with a segment named like the
+// stage.
+func stripStage(apigw awsevents.APIGatewayProxyRequest) string {
+	path, stage := apigw.Path, apigw.RequestContext.Stage
+
+	if stage == "" || stage == "$default" || path != apigw.RequestContext.Path {
+		return path
+	}
+
+	prefix := "/" + stage
+
+	resource := apigw.RequestContext.ResourcePath
+	if resource == prefix || strings.HasPrefix(resource, prefix+"/") {
+		return path
+	}
+
+	switch {
+	case path == prefix:
+		return "/"
+	case strings.HasPrefix(path, prefix+"/"):
+		return strings.TrimPrefix(path, prefix)
+	default:
+		return path
+	}
+}
+
 // Response mimics ALBTargetGroupResponse and APIGatewayV2HTTPResponse.
 type Response struct {
 	StatusCode        int                 `json:"statusCode"`
@@ -98,7 +327,6 @@ type Response struct {
 	MultiValueHeaders map[string][]string `json:"multiValueHeaders"`
 	Body              string              `json:"body"`
 	IsBase64Encoded   bool                `json:"isBase64Encoded"`
-	Cookies           []string            `json:"cookies"`
 }
 
 // AWSRequestToHTTPRequest converts an AWS Lambda request to a standard HTTP request.
@@ -163,6 +391,7 @@ func AWSRequestToHTTPRequest(ctx context.Context, event Request) (*http.Request,
 
 	req.RequestURI = u.RequestURI()
 	req.Header = headers
+	req.RemoteAddr = event.SourceIP()
 
 	return req.WithContext(ctx), nil
 }
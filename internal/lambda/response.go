@@ -3,9 +3,12 @@ package lambda
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime"
 	"net/http"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -15,20 +18,25 @@ const contentTypeHeaderKey = "Content-Type"
 // ProxyResponseWriter implements http.ResponseWriter and adds the method
 // necessary to return an events.ALBTargetGroupResponse object.
 type ProxyResponseWriter struct {
-	headers   http.Header
-	body      bytes.Buffer
-	status    int
-	observers []chan<- bool
+	headers          http.Header
+	body             bytes.Buffer
+	status           int
+	observers        []chan<- bool
+	binaryMediaTypes []string
 }
 
 // NewProxyResponseWriter returns a new ProxyResponseWriter object.
 // The object is initialized with an empty map of headers and a
 // status code of -1.
-func NewProxyResponseWriter() *ProxyResponseWriter {
+//
+// Responses whose Content-Type matches one of binaryMediaTypes are
+// always base64 encoded; see IsBinary.
+func NewProxyResponseWriter(binaryMediaTypes ...string) *ProxyResponseWriter {
 	return &ProxyResponseWriter{
-		headers:   make(http.Header),
-		status:    defaultStatusCode,
-		observers: make([]chan<- bool, 0),
+		headers:          make(http.Header),
+		status:           defaultStatusCode,
+		observers:        make([]chan<- bool, 0),
+		binaryMediaTypes: binaryMediaTypes,
 	}
 }
 
@@ -76,6 +84,17 @@ func (r *ProxyResponseWriter) Write(body []byte) (int, error) {
 	return n, nil
 }
 
+// Body returns the response body written so far.
+func (r *ProxyResponseWriter) Body() []byte {
+	return (&r.body).Bytes()
+}
+
+// StatusCode returns the status code written so far, or -1 if neither
+// WriteHeader nor Write has been called.
+func (r *ProxyResponseWriter) StatusCode() int {
+	return r.status
+}
+
 // WriteHeader sets a status code for the response. This method is used
 // for error responses.
 func (r *ProxyResponseWriter) WriteHeader(status int) {
@@ -95,15 +114,13 @@ func (r *ProxyResponseWriter) GetLambdaResponse() (Response, error) {
 
 	var output string
 
-	isBase64 := false
-
 	bb := (&r.body).Bytes()
 
-	if utf8.Valid(bb) {
-		output = string(bb)
-	} else {
+	isBase64 := IsBinary(r.headers, bb, r.binaryMediaTypes)
+	if isBase64 {
 		output = base64.StdEncoding.EncodeToString(bb)
-		isBase64 = true
+	} else {
+		output = string(bb)
 	}
 
 	headers := map[string]string{}
@@ -120,6 +137,104 @@ func (r *ProxyResponseWriter) GetLambdaResponse() (Response, error) {
 		MultiValueHeaders: r.headers,
 		Body:              output,
 		IsBase64Encoded:   isBase64,
-		Cookies:           []string{},
 	}, nil
 }
+
+// maxJSONEscapeFactor is the most a text body can grow when encoded as
+// a JSON string: a control character becomes a six-byte \u00XX escape.
+const maxJSONEscapeFactor = 6
+
+// EncodedSize returns the size in bytes of the JSON payload that the
+// Lambda runtime sends for the response.
+//
+// Marshalling is avoided when a cheap upper bound on the size is
+// already within limit; that bound is returned instead. The result is
+// therefore exact only when it may exceed limit.
+func (r Response) EncodedSize(limit int) int {
+	factor := maxJSONEscapeFactor
+	if r.IsBase64Encoded {
+		factor = 1
+	}
+
+	bound := len(r.Body)*factor + 256 // status code, field names and punctuation
+
+	for key, value := range r.Headers {
+		bound += (len(key) + len(value)) * maxJSONEscapeFactor
+	}
+
+	for key, values := range r.MultiValueHeaders {
+		for _, value := range values {
+			bound += (len(key) + len(value)) * maxJSONEscapeFactor
+		}
+	}
+
+	if bound <= limit {
+		return bound
+	}
+
+	payload, err := json.Marshal(r)
+	if err != nil {
+		return bound
+	}
+
+	return len(payload)
+}
+
+// IsBinary reports whether a response body must be base64 encoded in
+// the Lambda response. That is the case when:
+//
+//   - the response has a Content-Encoding (e.g. gzip), since compressed
+//     bodies are binary regardless of the media type
+//   - the Content-Type matches one of binaryMediaTypes
+//   - the body is not valid UTF-8
+//
+// Relying on UTF-8 detection alone is not enough: binary formats such
+// as protobuf can happen to be valid UTF-8 and would then be corrupted
+// when sent as text.
+func IsBinary(header http.Header, body []byte, binaryMediaTypes []string) bool {
+	if encoding := header.Get("Content-Encoding"); encoding != "" && !strings.EqualFold(encoding, "identity") {
+		return true
+	}
+
+	if MatchesMediaType(header.Get(contentTypeHeaderKey), binaryMediaTypes) {
+		return true
+	}
+
+	return !utf8.Valid(body)
+}
+
+// MatchesMediaType reports whether the media type of contentType
+// matches one of patterns. Matching is case-insensitive and ignores
+// parameters such as charset. A pattern ending in "*" matches any media
+// type with that prefix, e.g. "application/grpc*" matches
+// "application/grpc+proto".
+func MatchesMediaType(contentType string, patterns []string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+	}
+
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(mediaType, prefix) {
+				return true
+			}
+
+			continue
+		}
+
+		if mediaType == pattern {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,142 @@
+package lambda
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StreamingResponseWriter implements http.ResponseWriter and
+// http.Flusher on top of an io.Pipe, so that a handler's output can be
+// streamed to the Lambda runtime while it is being produced instead of
+// being buffered like ProxyResponseWriter does.
+//
+// The status code and headers are committed on the first call to
+// WriteHeader, Write or Flush; changes to the header map after that
+// are ignored, just like with net/http.
+type StreamingResponseWriter struct {
+	headers http.Header
+	status  int
+
+	reader *io.PipeReader
+	writer *io.PipeWriter
+
+	commitOnce sync.Once
+	ready      chan struct{}
+	committed  http.Header
+}
+
+// NewStreamingResponseWriter returns a new StreamingResponseWriter.
+func NewStreamingResponseWriter() *StreamingResponseWriter {
+	reader, writer := io.Pipe()
+
+	return &StreamingResponseWriter{
+		headers: make(http.Header),
+		status:  defaultStatusCode,
+		reader:  reader,
+		writer:  writer,
+		ready:   make(chan struct{}),
+	}
+}
+
+// Header implementation from the http.ResponseWriter interface.
+func (w *StreamingResponseWriter) Header() http.Header {
+	return w.headers
+}
+
+// WriteHeader commits the status code and headers of the response.
+// Subsequent calls are ignored.
+func (w *StreamingResponseWriter) WriteHeader(status int) {
+	w.commit(status)
+}
+
+// Write streams body to the reader returned by Body, committing the
+// response with status 200 OK first if needed. Write blocks until the
+// data has been consumed by the reader.
+func (w *StreamingResponseWriter) Write(body []byte) (int, error) {
+	if w.headers.Get(contentTypeHeaderKey) == "" {
+		w.headers.Set(contentTypeHeaderKey, http.DetectContentType(body))
+	}
+
+	w.commit(http.StatusOK)
+
+	n, err := w.writer.Write(body)
+	if err != nil {
+		return n, fmt.Errorf("failed to write response body: %w", err)
+	}
+
+	return n, nil
+}
+
+// Flush implements http.Flusher. The pipe is unbuffered, so written
+// data is already delivered; Flush only commits the headers so the
+// runtime can start streaming the response.
+func (w *StreamingResponseWriter) Flush() {
+	w.commit(http.StatusOK)
+}
+
+// Close commits the response if the handler wrote nothing and ends
+// the body stream. It must be called once the handler has returned.
+func (w *StreamingResponseWriter) Close() error {
+	w.commit(http.StatusOK)
+
+	return w.writer.Close() //nolint:wrapcheck // PipeWriter.Close always returns nil
+}
+
+// Ready returns a channel that is closed once the status code and
+// headers have been committed.
+func (w *StreamingResponseWriter) Ready() <-chan struct{} {
+	return w.ready
+}
+
+// StatusCode returns the committed status code. It must only be called
+// after Ready is closed.
+func (w *StreamingResponseWriter) StatusCode() int {
+	return w.status
+}
+
+// CommittedHeader returns a snapshot of the headers taken when the
+// response was committed. It must only be called after Ready is closed.
+func (w *StreamingResponseWriter) CommittedHeader() http.Header {
+	return w.committed
+}
+
+// Body returns the reader end of the response stream. Closing it makes
+// further writes by the handler fail, which stops abandoned handlers.
+func (w *StreamingResponseWriter) Body() io.ReadCloser {
+	return w.reader
+}
+
+func (w *StreamingResponseWriter) commit(status int) {
+	w.commitOnce.Do(func() {
+		if w.status == defaultStatusCode {
+			w.status = status
+		}
+
+		w.committed = w.headers.Clone()
+		close(w.ready)
+	})
+}
+
+// SplitHeaders converts multi-value headers into the single-value map
+// used by Function URL and API Gateway v2 responses. Repeated values
+// are joined with a comma, except Set-Cookie values, which cannot be
+// joined and are returned separately for the response's cookies field.
+func SplitHeaders(h http.Header) (map[string]string, []string) {
+	headers := make(map[string]string, len(h))
+	cookies := []string{}
+
+	for key, values := range h {
+		if http.CanonicalHeaderKey(key) == "Set-Cookie" {
+			cookies = append(cookies, values...)
+
+			continue
+		}
+
+		headers[key] = strings.Join(values, ",")
+	}
+
+	return headers, cookies
+}
@@ -0,0 +1,84 @@
+// Package requestid carries the ID that correlates a request across
+// logs and services. The ID is stored in the request context, echoed in
+// the X-Request-Id response header and forwarded on outbound calls.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+)
+
+// Header is the HTTP header that carries request IDs.
+const Header = "X-Request-Id"
+
+// maxLength bounds the length of incoming request IDs.
+const maxLength = 128
+
+type contextKey struct{}
+
+// WithID returns a copy of ctx that carries id.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID carried by ctx, or an empty string.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+
+	return id
+}
+
+// New generates a random request ID in the UUID version 4 format.
+func New() string {
+	var b [16]byte
+
+	_, _ = rand.Read(b[:]) // crypto/rand.Read never returns an error
+
+	b[6] = (b[6] & 0x0f) | 0x40 // version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	var buf [36]byte
+
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+
+	return string(buf[:])
+}
+
+// Valid reports whether an ID received from a client or upstream
+// service can be used as is: it must be at most 128 printable ASCII
+// characters without spaces, so that it cannot forge log lines.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+
+	for i := range len(id) {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TraceRoot returns the Root field of an X-Amzn-Trace-Id header, such
+// as "1-5759e988-bd862e3fe1be46a994272793", or an empty string.
+func TraceRoot(traceID string) string {
+	for field := range strings.SplitSeq(traceID, ";") {
+		if root, ok := strings.CutPrefix(strings.TrimSpace(field), "Root="); ok {
+			return root
+		}
+	}
+
+	return ""
+}
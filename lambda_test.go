@@ -0,0 +1,718 @@
+package dindenault_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/navigacontentlab/dindenault"
+)
+
+// echoHandler writes the request method, path, query and body back to
+// the client so tests can verify event conversion.
+func echoHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header()["X-Echo-Header"] = r.Header.Values("X-Multi")
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + "?" + r.URL.RawQuery + " " + string(body)))
+	})
+}
+
+func TestHandleAPIGatewayV1(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", echoHandler()),
+	)
+
+	handler := app.HandleAPIGatewayV1()
+
+	t.Run("converts multi-value headers and query strings", func(t *testing.T) {
+		resp, err := handler(context.Background(), events.APIGatewayProxyRequest{
+			Path:       "/api/items",
+			HTTPMethod: http.MethodPost,
+			Headers:    map[string]string{"X-Multi": "b"},
+			MultiValueHeaders: map[string][]string{
+				"X-Multi": {"a", "b"},
+			},
+			QueryStringParameters: map[string]string{"tag": "y"},
+			MultiValueQueryStringParameters: map[string][]string{
+				"tag": {"x", "y"},
+			},
+			Body: "hello",
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+		}
+
+		if resp.Body != "POST /api/items?tag=x&tag=y hello" {
+			t.Errorf("Unexpected body %q", resp.Body)
+		}
+
+		if got := resp.MultiValueHeaders["X-Echo-Header"]; strings.Join(got, ",") != "a,b" {
+			t.Errorf("Expected headers not to be duplicated, got %v", got)
+		}
+	})
+
+	t.Run("strips stage prefix and decodes base64 body", func(t *testing.T) {
+		resp, err := handler(context.Background(), events.APIGatewayProxyRequest{
+			Path:            "/prod/api/items",
+			HTTPMethod:      http.MethodPut,
+			Body:            base64.StdEncoding.EncodeToString([]byte("binary")),
+			IsBase64Encoded: true,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Stage:        "prod",
+				Path:         "/prod/api/items",
+				ResourcePath: "/api/{proxy+}",
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if resp.Body != "PUT /api/items? binary" {
+			t.Errorf("Unexpected body %q", resp.Body)
+		}
+	})
+
+	t.Run("keeps route segment named like the stage", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithService("/v1/", echoHandler()),
+		)
+
+		tests := []struct {
+			name string
+			ctx  events.APIGatewayProxyRequestContext
+		}{
+			{
+				name: "execute-api endpoint",
+				ctx: events.APIGatewayProxyRequestContext{
+					Stage:        "v1",
+					Path:         "/v1/v1/articles",
+					ResourcePath: "/v1/{proxy+}",
+				},
+			},
+			{
+				name: "custom domain",
+				ctx: events.APIGatewayProxyRequestContext{
+					Stage:        "v1",
+					Path:         "/v1/articles",
+					ResourcePath: "/v1/{proxy+}",
+				},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				resp, err := app.HandleAPIGatewayV1()(context.Background(), events.APIGatewayProxyRequest{
+					Path:           "/v1/articles",
+					HTTPMethod:     http.MethodGet,
+					RequestContext: tt.ctx,
+				})
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+
+				if resp.StatusCode != http.StatusOK || resp.Body != "GET /v1/articles? " {
+					t.Errorf("Unexpected response %d %q", resp.StatusCode, resp.Body)
+				}
+			})
+		}
+	})
+
+	t.Run("returns 404 for unknown paths", func(t *testing.T) {
+		resp, err := handler(context.Background(), events.APIGatewayProxyRequest{
+			Path:       "/other",
+			HTTPMethod: http.MethodGet,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestHandleFunctionURL(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			http.SetCookie(w, &http.Cookie{Name: "a", Value: "1"})
+			http.SetCookie(w, &http.Cookie{Name: "b", Value: "2"})
+			w.Header().Add("X-Multi", "x")
+			w.Header().Add("X-Multi", "y")
+			_, _ = w.Write([]byte("ok"))
+		})),
+	)
+
+	resp, err := app.HandleFunctionURL()(context.Background(), events.LambdaFunctionURLRequest{
+		RawPath: "/api/items",
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Body != "ok" {
+		t.Errorf("Unexpected response %d %q", resp.StatusCode, resp.Body)
+	}
+
+	if resp.Headers["X-Multi"] != "x,y" {
+		t.Errorf("Expected joined header, got %q", resp.Headers["X-Multi"])
+	}
+
+	if strings.Join(resp.Cookies, ";") != "a=1;b=2" {
+		t.Errorf("Expected cookies [a=1 b=2], got %v", resp.Cookies)
+	}
+}
+
+func TestHandleFunctionURLStreaming(t *testing.T) {
+	release := make(chan struct{})
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/stream", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusAccepted)
+			_, _ = w.Write([]byte("first "))
+
+			// The response must be returned to the runtime before the
+			// handler has finished writing.
+			<-release
+
+			_, _ = w.Write([]byte("second"))
+		})),
+	)
+
+	resp, err := app.HandleFunctionURLStreaming()(context.Background(), events.LambdaFunctionURLRequest{
+		RawPath: "/stream",
+		RequestContext: events.LambdaFunctionURLRequestContext{
+			HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202, got %d", resp.StatusCode)
+	}
+
+	if resp.Headers["Content-Type"] != "text/plain" {
+		t.Errorf("Expected content type text/plain, got %q", resp.Headers["Content-Type"])
+	}
+
+	first := make([]byte, len("first "))
+	if _, err := io.ReadFull(resp.Body, first); err != nil || string(first) != "first " {
+		t.Fatalf("Expected first chunk, got %q (%v)", first, err)
+	}
+
+	close(release)
+
+	rest, err := io.ReadAll(resp.Body)
+	if err != nil || string(rest) != "second" {
+		t.Errorf("Expected second chunk, got %q (%v)", rest, err)
+	}
+}
+
+func TestHandleAny(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", echoHandler()),
+	)
+
+	handler := app.HandleAny()
+
+	tests := []struct {
+		name     string
+		payload  string
+		wantType any
+	}{
+		{
+			name: "ALB",
+			payload: `{"httpMethod":"GET","path":"/api/items","headers":{},
+				"requestContext":{"elb":{"targetGroupArn":"arn:aws:elasticloadbalancing:tg"}}}`,
+			wantType: events.ALBTargetGroupResponse{},
+		},
+		{
+			name: "API Gateway v1",
+			payload: `{"httpMethod":"GET","path":"/api/items","resource":"/{proxy+}",
+				"requestContext":{"stage":"prod","requestId":"abc","httpMethod":"GET"}}`,
+			wantType: events.APIGatewayProxyResponse{},
+		},
+		{
+			name: "API Gateway v2",
+			payload: `{"version":"2.0","rawPath":"/api/items","routeKey":"$default",
+				"requestContext":{"domainName":"abc.execute-api.eu-west-1.amazonaws.com","http":{"method":"GET"}}}`,
+			wantType: events.APIGatewayV2HTTPResponse{},
+		},
+		{
+			name: "Function URL",
+			payload: `{"version":"2.0","rawPath":"/api/items",
+				"requestContext":{"domainName":"abc.lambda-url.eu-west-1.on.aws","http":{"method":"GET"}}}`,
+			wantType: events.LambdaFunctionURLResponse{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler(context.Background(), []byte(tt.payload))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if reflect.TypeOf(resp) != reflect.TypeOf(tt.wantType) {
+				t.Fatalf("Expected response type %T, got %T", tt.wantType, resp)
+			}
+
+			body := reflect.ValueOf(resp).FieldByName("Body").String()
+			if body != "GET /api/items? " {
+				t.Errorf("Unexpected body %q", body)
+			}
+		})
+	}
+
+	t.Run("rejects unknown events", func(t *testing.T) {
+		if _, err := handler(context.Background(), []byte(`{"Records":[]}`)); err == nil {
+			t.Error("Expected error for non-HTTP event")
+		}
+	})
+}
+
+func TestLambdaRequestContext(t *testing.T) {
+	var (
+		got        dindenault.LambdaRequestInfo
+		ok         bool
+		remoteAddr string
+	)
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, ok = dindenault.LambdaRequestContext(r.Context())
+			remoteAddr = r.RemoteAddr
+
+			w.WriteHeader(http.StatusNoContent)
+		})),
+	)
+
+	t.Run("API Gateway v2", func(t *testing.T) {
+		_, err := app.HandleAPIGateway()(context.Background(), events.APIGatewayV2HTTPRequest{
+			Version: "2.0",
+			RawPath: "/api/items",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				RequestID: "req-123",
+				Stage:     "prod",
+				HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{
+					Method:   http.MethodGet,
+					SourceIP: "203.0.113.7",
+				},
+				Authorizer: &events.APIGatewayV2HTTPRequestContextAuthorizerDescription{
+					Lambda: map[string]any{"tenant": "acme"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if !ok {
+			t.Fatal("Expected Lambda request info in context")
+		}
+
+		if got.EventType != "apigateway-v2" || got.RequestID != "req-123" || got.Stage != "prod" {
+			t.Errorf("Unexpected request info %+v", got)
+		}
+
+		if remoteAddr != "203.0.113.7" || got.SourceIP != "203.0.113.7" {
+			t.Errorf("Expected source IP 203.0.113.7, got RemoteAddr %q, SourceIP %q", remoteAddr, got.SourceIP)
+		}
+
+		lambdaAuth, _ := got.Authorizer["lambda"].(map[string]any)
+		if lambdaAuth["tenant"] != "acme" {
+			t.Errorf("Expected authorizer context, got %v", got.Authorizer)
+		}
+	})
+
+	t.Run("ALB", func(t *testing.T) {
+		_, err := app.Handle()(context.Background(), events.ALBTargetGroupRequest{
+			HTTPMethod: http.MethodGet,
+			Path:       "/api/items",
+			Headers: map[string]string{
+				"x-forwarded-for":  "192.0.2.99, 198.51.100.1",
+				"x-amzn-trace-id":  "Root=1-abc",
+				"host":             "example.com",
+				"x-forwarded-port": "443",
+			},
+			RequestContext: events.ALBTargetGroupRequestContext{
+				ELB: events.ELBContext{TargetGroupArn: "arn:tg"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if got.EventType != "alb" || got.TargetGroupARN != "arn:tg" || got.TraceID != "Root=1-abc" {
+			t.Errorf("Unexpected request info %+v", got)
+		}
+
+		if remoteAddr != "198.51.100.1" {
+			t.Errorf("Expected RemoteAddr from X-Forwarded-For, got %q", remoteAddr)
+		}
+	})
+
+	t.Run("not available outside Lambda", func(t *testing.T) {
+		if _, ok := dindenault.LambdaRequestContext(context.Background()); ok {
+			t.Error("Expected no Lambda request info")
+		}
+	})
+}
+
+func TestHandleAPIGatewayCookies(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			session, err := r.Cookie("session")
+			if err != nil {
+				http.Error(w, "missing session", http.StatusUnauthorized)
+
+				return
+			}
+
+			theme, _ := r.Cookie("theme")
+
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: session.Value + "-renewed"})
+			http.SetCookie(w, &http.Cookie{Name: "seen", Value: "1"})
+			_, _ = w.Write([]byte(theme.Value))
+		})),
+	)
+
+	resp, err := app.HandleAPIGateway()(context.Background(), events.APIGatewayV2HTTPRequest{
+		Version: "2.0",
+		RawPath: "/api/profile",
+		Cookies: []string{"session=abc", "theme=dark"},
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK || resp.Body != "dark" {
+		t.Fatalf("Expected cookies to reach the handler, got %d %q", resp.StatusCode, resp.Body)
+	}
+
+	if strings.Join(resp.Cookies, ";") != "session=abc-renewed;seen=1" {
+		t.Errorf("Expected Set-Cookie values in Cookies, got %v", resp.Cookies)
+	}
+
+	if _, ok := resp.Headers["Set-Cookie"]; ok {
+		t.Error("Expected Set-Cookie to be removed from headers")
+	}
+}
+
+func TestBinaryResponseEncoding(t *testing.T) {
+	writeWith := func(contentType, encoding, body string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+
+			if encoding != "" {
+				w.Header().Set("Content-Encoding", encoding)
+			}
+
+			_, _ = w.Write([]byte(body))
+		})
+	}
+
+	tests := []struct {
+		name        string
+		options     []dindenault.Option
+		contentType string
+		encoding    string
+		wantBase64  bool
+	}{
+		{name: "connect proto", contentType: "application/connect+proto", wantBase64: true},
+		{name: "proto", contentType: "application/proto", wantBase64: true},
+		{name: "grpc-web", contentType: "application/grpc-web+proto", wantBase64: true},
+		{name: "gzip json", contentType: "application/json", encoding: "gzip", wantBase64: true},
+		{name: "json", contentType: "application/json; charset=utf-8", wantBase64: false},
+		{
+			name:        "custom media types",
+			options:     []dindenault.Option{dindenault.WithBinaryMediaTypes("application/pdf")},
+			contentType: "application/pdf",
+			wantBase64:  true,
+		},
+		{
+			name:        "custom media types replace defaults",
+			options:     []dindenault.Option{dindenault.WithBinaryMediaTypes("application/pdf")},
+			contentType: "application/proto",
+			wantBase64:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A body that is valid UTF-8 must still be encoded for
+			// binary media types.
+			opts := append([]dindenault.Option{
+				dindenault.WithService("/api/", writeWith(tt.contentType, tt.encoding, "text")),
+			}, tt.options...)
+
+			app := dindenault.New(slog.Default(), opts...)
+
+			resp, err := app.Handle()(context.Background(), events.ALBTargetGroupRequest{
+				HTTPMethod: http.MethodPost,
+				Path:       "/api/method",
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if resp.IsBase64Encoded != tt.wantBase64 {
+				t.Fatalf("Expected IsBase64Encoded=%v, got %v", tt.wantBase64, resp.IsBase64Encoded)
+			}
+
+			want := "text"
+			if tt.wantBase64 {
+				want = base64.StdEncoding.EncodeToString([]byte("text"))
+			}
+
+			if resp.Body != want {
+				t.Errorf("Expected body %q, got %q", want, resp.Body)
+			}
+		})
+	}
+}
+
+func TestMaxResponseSize(t *testing.T) {
+	large := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("x", 2048)))
+	})
+
+	invoke := func(t *testing.T, app *dindenault.App, headers map[string]string) events.ALBTargetGroupResponse {
+		t.Helper()
+
+		resp, err := app.Handle()(context.Background(), events.ALBTargetGroupRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       "/api/method",
+			Headers:    headers,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return resp
+	}
+
+	t.Run("responses within the limit pass through", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithMaxResponseSize(4096),
+			dindenault.WithService("/api/", large),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusOK || len(resp.Body) != 2048 {
+			t.Errorf("Expected the original response, got %d with %d bytes", resp.StatusCode, len(resp.Body))
+		}
+	})
+
+	t.Run("plain HTTP requests get 413", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithMaxResponseSize(1024),
+			dindenault.WithService("/api/", large),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusRequestEntityTooLarge {
+			t.Errorf("Expected status 413, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("connect requests get resource_exhausted", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithMaxResponseSize(1024),
+			dindenault.WithService("/api/", large),
+		)
+
+		resp := invoke(t, app, map[string]string{
+			"Content-Type":             "application/json",
+			"Connect-Protocol-Version": "1",
+		})
+
+		var connectErr struct {
+			Code string `json:"code"`
+		}
+
+		if err := json.Unmarshal([]byte(resp.Body), &connectErr); err != nil {
+			t.Fatalf("Expected a Connect error body, got %q: %v", resp.Body, err)
+		}
+
+		if connectErr.Code != "resource_exhausted" {
+			t.Errorf("Expected code resource_exhausted, got %q", connectErr.Code)
+		}
+	})
+
+	t.Run("oversized response handler replaces the response", func(t *testing.T) {
+		var gotBody int
+
+		app := dindenault.New(slog.Default(),
+			dindenault.WithMaxResponseSize(1024),
+			dindenault.WithOversizedResponseHandler(
+				func(w http.ResponseWriter, r *http.Request, header http.Header, body []byte) {
+					gotBody = len(body)
+
+					if header.Get("Content-Type") != "text/plain" {
+						t.Errorf("Expected original headers, got %v", header)
+					}
+
+					http.Redirect(w, r, "https://bucket.example.com/object", http.StatusSeeOther)
+				}),
+			dindenault.WithService("/api/", large),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusSeeOther {
+			t.Errorf("Expected status 303, got %d", resp.StatusCode)
+		}
+
+		if gotBody != 2048 {
+			t.Errorf("Expected handler to receive the 2048 byte body, got %d", gotBody)
+		}
+	})
+
+	t.Run("zero disables the limit", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithMaxResponseSize(0),
+			dindenault.WithService("/api/", large),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("Expected status 200, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestDeadlineMargin(t *testing.T) {
+	// stuck ignores its context, like a handler blocked on a call
+	// without a timeout.
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+
+	stuck := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+
+	invoke := func(t *testing.T, app *dindenault.App, headers map[string]string) events.ALBTargetGroupResponse {
+		t.Helper()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		resp, err := app.Handle()(ctx, events.ALBTargetGroupRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       "/api/method",
+			Headers:    headers,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return resp
+	}
+
+	t.Run("handler context expires before the Lambda deadline", func(t *testing.T) {
+		var remaining time.Duration
+
+		app := dindenault.New(slog.Default(),
+			dindenault.WithDeadlineMargin(500*time.Millisecond),
+			dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				deadline, _ := r.Context().Deadline()
+				remaining = time.Until(deadline)
+
+				w.WriteHeader(http.StatusOK)
+			})),
+		)
+
+		invoke(t, app, nil)
+
+		if remaining <= 0 || remaining > 500*time.Millisecond {
+			t.Errorf("Expected at most 500ms left for the handler, got %v", remaining)
+		}
+	})
+
+	t.Run("overrunning plain request gets 504", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithDeadlineMargin(900*time.Millisecond),
+			dindenault.WithService("/api/", stuck),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Errorf("Expected status 504, got %d", resp.StatusCode)
+		}
+	})
+
+	t.Run("overrunning connect request gets deadline_exceeded", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithDeadlineMargin(900*time.Millisecond),
+			dindenault.WithService("/api/", stuck),
+		)
+
+		resp := invoke(t, app, map[string]string{
+			"Content-Type":             "application/json",
+			"Connect-Protocol-Version": "1",
+		})
+
+		var connectErr struct {
+			Code string `json:"code"`
+		}
+
+		if err := json.Unmarshal([]byte(resp.Body), &connectErr); err != nil {
+			t.Fatalf("Expected a Connect error body, got %q: %v", resp.Body, err)
+		}
+
+		if connectErr.Code != "deadline_exceeded" {
+			t.Errorf("Expected code deadline_exceeded, got %q", connectErr.Code)
+		}
+	})
+
+	t.Run("overrunning streaming request gets 504", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithDeadlineMargin(900*time.Millisecond),
+			dindenault.WithService("/api/", stuck),
+		)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		resp, err := app.HandleFunctionURLStreaming()(ctx, events.LambdaFunctionURLRequest{
+			RawPath: "/api/method",
+			RequestContext: events.LambdaFunctionURLRequestContext{
+				HTTP: events.LambdaFunctionURLRequestContextHTTPDescription{Method: http.MethodPost},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if resp.StatusCode != http.StatusGatewayTimeout {
+			t.Errorf("Expected status 504, got %d", resp.StatusCode)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package dindenault
+
+import (
+	"context"
+
+	"github.com/navigacontentlab/dindenault/internal/lambda"
+)
+
+// LambdaRequestInfo holds metadata about the Lambda event that carried
+// a request. Fields that the event type does not provide are empty.
+type LambdaRequestInfo struct {
+	// EventType is the trigger that produced the event: "alb",
+	// "apigateway-v1", "apigateway-v2" or "function-url".
+	EventType string
+	// RequestID is the API Gateway or Function URL request ID.
+	RequestID string
+	// TraceID is the X-Amzn-Trace-Id header added by ALB and
+	// API Gateway.
+	TraceID string
+	// SourceIP is the client IP address. For ALB events it is taken
+	// from the X-Forwarded-For entry appended by the load balancer.
+	SourceIP string
+	// UserAgent is the client user agent reported by API Gateway.
+	UserAgent string
+	// Stage is the API Gateway stage.
+	Stage string
+	// APIID is the API Gateway API ID or the Function URL ID.
+	APIID string
+	// AccountID is the AWS account that owns the API.
+	AccountID string
+	// DomainName is the domain name the request was sent to.
+	DomainName string
+	// TargetGroupARN is the ALB target group that received the request.
+	TargetGroupARN string
+	// Authorizer is the API Gateway authorizer context. For HTTP APIs
+	// (v2) and Function URLs it is keyed by authorizer type ("jwt",
+	// "lambda" or "iam").
+	Authorizer map[string]any
+}
+
+type lambdaRequestInfoKey struct{}
+
+// LambdaRequestContext returns metadata about the Lambda event that
+// carried the current request. It reports false for requests that were
+// not served from a Lambda event, for example through HTTPHandler.
+//
+// The client IP is also available as http.Request.RemoteAddr.
+//
+// Example:
+//
+//	if info, ok := dindenault.LambdaRequestContext(ctx); ok {
+//	    logger.Info("Audit", "client_ip", info.SourceIP, "gateway_request_id", info.RequestID)
+//	}
+func LambdaRequestContext(ctx context.Context) (LambdaRequestInfo, bool) {
+	info, ok := ctx.Value(lambdaRequestInfoKey{}).(LambdaRequestInfo)
+
+	return info, ok
+}
+
+// withLambdaRequestInfo stores the metadata of a Lambda event in ctx.
+func withLambdaRequestInfo(ctx context.Context, request lambda.Request) context.Context {
+	rc := request.RequestContext
+
+	return context.WithValue(ctx, lambdaRequestInfoKey{}, LambdaRequestInfo{
+		EventType:      request.EventType.String(),
+		RequestID:      rc.RequestID,
+		TraceID:        request.TraceID(),
+		SourceIP:       request.SourceIP(),
+		UserAgent:      rc.HTTP.UserAgent,
+		Stage:          rc.Stage,
+		APIID:          rc.APIID,
+		AccountID:      rc.AccountID,
+		DomainName:     rc.DomainName,
+		TargetGroupARN: rc.ELB.TargetGroupArn,
+		Authorizer:     rc.Authorizer,
+	})
+}
@@ -0,0 +1,366 @@
+package dindenault
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/navigacontentlab/dindenault/internal/interceptors"
+	"github.com/navigacontentlab/dindenault/internal/requestid"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// redactedValue replaces the values of sensitive log attributes.
+const redactedValue = "[REDACTED]"
+
+// defaultRedactKeys are attribute keys whose values are always
+// redacted unless redaction is disabled. Keys ending in "_token",
+// "_secret" or "password" are redacted as well.
+var defaultRedactKeys = []string{
+	"authorization", "proxy-authorization", "cookie", "set-cookie",
+	"x-imid-token", "token", "secret", "api_key", "apikey",
+}
+
+// LogOptions configures the enrichment and redaction done by
+// NewContextHandler. The zero value enables everything.
+type LogOptions struct {
+	// DisableClaims stops adding the authenticated caller's
+	// organisation ("org"), subject ("sub") and token type
+	// ("token_type").
+	DisableClaims bool
+
+	// DisableProcedure stops adding the Connect procedure, service and
+	// method ("procedure", "service", "method").
+	DisableProcedure bool
+
+	// DisableLambdaMetadata stops adding the "lambda" group with the
+	// event type, trace ID and API Gateway stage.
+	DisableLambdaMetadata bool
+
+	// RedactKeys are additional attribute keys, matched
+	// case-insensitively, whose values are replaced by "[REDACTED]".
+	RedactKeys []string
+
+	// DisableRedaction stops redacting sensitive attributes. By default
+	// credentials such as "authorization" or "password", and sensitive
+	// entries of http.Header values, are never written to the log.
+	DisableRedaction bool
+}
+
+// WithLogOptions configures how the App enriches and redacts its own
+// log records and those of loggers returned by LoggerFromContext. It
+// applies to components created by other options, whatever their order.
+func WithLogOptions(opts LogOptions) Option {
+	return func(a *App) {
+		a.logOptions = opts
+	}
+}
+
+// NewContextHandler returns a slog.Handler that enriches records logged
+// with a request context before passing them to next. It adds the
+// request ID ("request_id"), the authenticated caller's claims, the
+// Connect procedure and Lambda metadata, and redacts sensitive
+// attributes; see LogOptions. Attributes that a record already has are
+// not added again.
+//
+// Records must be logged with a context to be enriched, for example
+// with logger.InfoContext(ctx, ...), or through LoggerFromContext.
+//
+// The App wraps its own logger with NewContextHandler; wrap the
+// handler of other loggers to get the same enrichment:
+//
+//	logger := slog.New(dindenault.NewContextHandler(
+//	    slog.NewJSONHandler(os.Stdout, nil),
+//	    dindenault.LogOptions{RedactKeys: []string{"ssn"}},
+//	))
+//
+//nolint:ireturn // slog.Handler is the intended abstraction
+func NewContextHandler(next slog.Handler, opts LogOptions) slog.Handler {
+	if h, ok := next.(*contextHandler); ok && len(h.groups) == 0 {
+		next = h.next
+	}
+
+	h := &contextHandler{next: next, settings: new(atomic.Pointer[logSettings])}
+	h.configure(opts)
+
+	return h
+}
+
+// logSettings are the options of a contextHandler, shared with the
+// handlers derived from it.
+type logSettings struct {
+	opts       LogOptions
+	redactKeys map[string]bool
+}
+
+// configure replaces the options of h and of the handlers derived from
+// it. The App uses it to apply WithLogOptions to the loggers that its
+// options took before all options were applied.
+func (h *contextHandler) configure(opts LogOptions) {
+	settings := &logSettings{opts: opts}
+
+	if !opts.DisableRedaction {
+		settings.redactKeys = make(map[string]bool)
+
+		for _, key := range slices.Concat(defaultRedactKeys, opts.RedactKeys) {
+			settings.redactKeys[strings.ToLower(key)] = true
+		}
+	}
+
+	h.settings.Store(settings)
+}
+
+// LoggerFromContext returns a logger that enriches its records from
+// ctx, as described for NewContextHandler, without the need to use the
+// *Context logging methods:
+//
+//	func (s *Service) Get(ctx context.Context, req *connect.Request[v1.GetRequest]) (...) {
+//	    logger := dindenault.LoggerFromContext(ctx)
+//	    logger.Info("Fetching article", "uuid", req.Msg.Uuid)
+//	    ...
+//	}
+//
+// Within a request served by an App the logger writes through the App's
+// logger; otherwise it uses slog.Default.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	handler, ok := ctx.Value(loggerContextKey{}).(*contextHandler)
+	if !ok {
+		handler, _ = NewContextHandler(slog.Default().Handler(), LogOptions{}).(*contextHandler)
+	}
+
+	return slog.New(&boundHandler{contextHandler: handler, ctx: ctx})
+}
+
+type (
+	loggerContextKey    struct{}
+	procedureContextKey struct{}
+)
+
+// withLogContext stores the App's logger and, for Connect requests,
+// the procedure in the request context.
+func (a *App) withLogContext(req *http.Request, pattern string) *http.Request {
+	ctx := req.Context()
+
+	if handler, ok := a.logger.Handler().(*contextHandler); ok {
+		ctx = context.WithValue(ctx, loggerContextKey{}, handler)
+	}
+
+	// Service patterns may be prefixed with a method.
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+
+	if _, ok := serviceName(pattern); ok {
+		ctx = context.WithValue(ctx, procedureContextKey{}, req.URL.Path)
+	}
+
+	return req.WithContext(ctx)
+}
+
+// contextHandler is the slog.Handler returned by NewContextHandler.
+//
+// Context attributes are added at the top level of a record, also for
+// loggers derived with WithGroup. Groups, and the attributes added
+// within them, are therefore kept in groups and applied to the record
+// in Handle instead of being passed on to next.
+type contextHandler struct {
+	next     slog.Handler
+	groups   []logGroup
+	settings *atomic.Pointer[logSettings]
+}
+
+// logGroup is a group opened with WithGroup and the attributes added
+// to it with WithAttrs.
+type logGroup struct {
+	name  string
+	attrs []slog.Attr
+}
+
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := make([]slog.Attr, 0, record.NumAttrs())
+
+	record.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, h.redact(attr))
+
+		return true
+	})
+
+	// Nest the record's attributes in the open groups, innermost first.
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		group := h.groups[i]
+
+		attrs = append(slices.Clip(group.attrs), attrs...)
+		if len(attrs) > 0 {
+			attrs = []slog.Attr{{Key: group.name, Value: slog.GroupValue(attrs...)}}
+		}
+	}
+
+	existing := make(map[string]bool, len(attrs))
+	enriched := slog.NewRecord(record.Time, record.Level, record.Message, record.PC)
+
+	for _, attr := range attrs {
+		existing[attr.Key] = true
+		enriched.AddAttrs(attr)
+	}
+
+	for _, attr := range h.contextAttrs(ctx) {
+		if !existing[attr.Key] {
+			enriched.AddAttrs(attr)
+		}
+	}
+
+	return h.next.Handle(ctx, enriched) //nolint:wrapcheck // transparent wrapper
+}
+
+//nolint:ireturn // slog.Handler requires returning the interface
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redact(attr)
+	}
+
+	if len(h.groups) == 0 {
+		return &contextHandler{next: h.next.WithAttrs(redacted), settings: h.settings}
+	}
+
+	groups := slices.Clone(h.groups)
+	last := &groups[len(groups)-1]
+	last.attrs = slices.Concat(last.attrs, redacted)
+
+	return &contextHandler{next: h.next, groups: groups, settings: h.settings}
+}
+
+//nolint:ireturn // slog.Handler requires returning the interface
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	groups := append(slices.Clip(h.groups), logGroup{name: name})
+
+	return &contextHandler{next: h.next, groups: groups, settings: h.settings}
+}
+
+// contextAttrs returns the attributes derived from ctx.
+func (h *contextHandler) contextAttrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+
+	opts := h.settings.Load().opts
+
+	if id := requestid.FromContext(ctx); id != "" {
+		attrs = append(attrs, slog.String("request_id", id))
+	}
+
+	if !opts.DisableProcedure {
+		if procedure, ok := ctx.Value(procedureContextKey{}).(string); ok {
+			service, method := interceptors.ExtractServiceAndMethod(procedure)
+
+			attrs = append(attrs,
+				slog.String("procedure", procedure),
+				slog.String("service", service),
+				slog.String("method", method))
+		}
+	}
+
+	if !opts.DisableClaims {
+		if auth, err := navigaid.GetAuth(ctx); err == nil {
+			attrs = append(attrs,
+				slog.String("org", auth.Claims.Org),
+				slog.String("sub", auth.Claims.Subject),
+				slog.String("token_type", auth.Claims.TokenType))
+		}
+	}
+
+	if !opts.DisableLambdaMetadata {
+		if info, ok := LambdaRequestContext(ctx); ok {
+			lambdaAttrs := []any{slog.String("event_type", info.EventType)}
+
+			if info.TraceID != "" {
+				lambdaAttrs = append(lambdaAttrs, slog.String("trace_id", info.TraceID))
+			}
+
+			if info.Stage != "" {
+				lambdaAttrs = append(lambdaAttrs, slog.String("stage", info.Stage))
+			}
+
+			attrs = append(attrs, slog.Group("lambda", lambdaAttrs...))
+		}
+	}
+
+	return attrs
+}
+
+// redact masks the value of attr if its key is sensitive, recursing
+// into groups and masking sensitive entries of http.Header values.
+func (h *contextHandler) redact(attr slog.Attr) slog.Attr {
+	redactKeys := h.settings.Load().redactKeys
+	if redactKeys == nil {
+		return attr
+	}
+
+	if isSensitive(redactKeys, attr.Key) {
+		return slog.String(attr.Key, redactedValue)
+	}
+
+	switch attr.Value.Kind() {
+	case slog.KindGroup:
+		group := attr.Value.Group()
+
+		redacted := make([]slog.Attr, len(group))
+		for i, a := range group {
+			redacted[i] = h.redact(a)
+		}
+
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		if header, ok := attr.Value.Any().(http.Header); ok {
+			return slog.Any(attr.Key, redactHeaders(header))
+		}
+	default:
+	}
+
+	return attr
+}
+
+// isSensitive reports whether values of key are redacted.
+func isSensitive(redactKeys map[string]bool, key string) bool {
+	key = strings.ToLower(key)
+
+	return redactKeys[key] ||
+		strings.HasSuffix(key, "_token") ||
+		strings.HasSuffix(key, "_secret") ||
+		strings.HasSuffix(key, "password")
+}
+
+// boundHandler enriches records from a fixed context, for loggers
+// returned by LoggerFromContext.
+type boundHandler struct {
+	*contextHandler
+
+	ctx context.Context //nolint:containedctx // the logger is bound to a request
+}
+
+func (h *boundHandler) Handle(_ context.Context, record slog.Record) error {
+	return h.contextHandler.Handle(h.ctx, record)
+}
+
+//nolint:ireturn // slog.Handler requires returning the interface
+func (h *boundHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	next, _ := h.contextHandler.WithAttrs(attrs).(*contextHandler)
+
+	return &boundHandler{contextHandler: next, ctx: h.ctx}
+}
+
+//nolint:ireturn // slog.Handler requires returning the interface
+func (h *boundHandler) WithGroup(name string) slog.Handler {
+	next, _ := h.contextHandler.WithGroup(name).(*contextHandler)
+
+	return &boundHandler{contextHandler: next, ctx: h.ctx}
+}
@@ -0,0 +1,237 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+func TestLoggerFromContext(t *testing.T) {
+	var buf bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := navigaid.SetAuth(r.Context(), navigaid.AuthInfo{
+			AccessToken: "secret-token",
+			Claims: navigaid.Claims{
+				RegisteredClaims: jwt.RegisteredClaims{Subject: "user-1"},
+				Org:              "acme",
+				TokenType:        "access_token",
+			},
+		}, nil)
+
+		dindenault.LoggerFromContext(ctx).With("api_key", "hunter2").Info("Handled",
+			"password", "hunter2",
+			"headers", http.Header{"Authorization": {"Bearer hunter2"}, "Accept": {"*/*"}},
+			slog.Group("upstream", "client_secret", "hunter2", "status", 200),
+		)
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(t *testing.T, app *dindenault.App, path string) map[string]any {
+		t.Helper()
+
+		buf.Reset()
+
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("X-Request-Id", "req-42")
+
+		app.HTTPHandler().ServeHTTP(httptest.NewRecorder(), req)
+
+		if strings.Contains(buf.String(), "hunter2") {
+			t.Errorf("Sensitive values were logged: %s", buf.String())
+		}
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid log record %q: %v", buf.String(), err)
+		}
+
+		return record
+	}
+
+	t.Run("enriches records", func(t *testing.T) {
+		app := dindenault.New(logger,
+			dindenault.WithPlainService("/pkg.v1.Service/", logHandler))
+
+		record := serve(t, app, "/pkg.v1.Service/Get")
+
+		expected := map[string]any{
+			"request_id": "req-42",
+			"procedure":  "/pkg.v1.Service/Get",
+			"service":    "Service",
+			"method":     "Get",
+			"org":        "acme",
+			"sub":        "user-1",
+			"token_type": "access_token",
+			"password":   "[REDACTED]",
+			"api_key":    "[REDACTED]",
+		}
+
+		for key, value := range expected {
+			if record[key] != value {
+				t.Errorf("Expected %s=%v, got %v", key, value, record[key])
+			}
+		}
+
+		headers, _ := record["headers"].(map[string]any)
+		if accept, _ := headers["Accept"].([]any); len(accept) != 1 || accept[0] != "*/*" {
+			t.Errorf("Expected non-sensitive headers to be kept, got %v", headers)
+		}
+	})
+
+	t.Run("plain paths have no procedure", func(t *testing.T) {
+		app := dindenault.New(logger, dindenault.WithPlainService("/webhook", logHandler))
+
+		record := serve(t, app, "/webhook")
+
+		if _, ok := record["procedure"]; ok {
+			t.Errorf("Expected no procedure, got %v", record["procedure"])
+		}
+	})
+
+	t.Run("enrichment can be disabled", func(t *testing.T) {
+		app := dindenault.New(logger,
+			dindenault.WithLogOptions(dindenault.LogOptions{
+				DisableClaims:    true,
+				DisableProcedure: true,
+			}),
+			dindenault.WithPlainService("/pkg.v1.Service/", logHandler))
+
+		record := serve(t, app, "/pkg.v1.Service/Get")
+
+		for _, key := range []string{"procedure", "org", "sub"} {
+			if _, ok := record[key]; ok {
+				t.Errorf("Expected no %s, got %v", key, record[key])
+			}
+		}
+
+		if record["request_id"] != "req-42" {
+			t.Errorf("Expected request_id to be kept, got %v", record["request_id"])
+		}
+	})
+}
+
+func TestNewContextHandler(t *testing.T) {
+	var buf bytes.Buffer
+
+	handler := slog.NewJSONHandler(&buf, nil)
+
+	t.Run("custom keys", func(t *testing.T) {
+		buf.Reset()
+
+		logger := slog.New(dindenault.NewContextHandler(handler, dindenault.LogOptions{
+			RedactKeys: []string{"SSN"},
+		}))
+		logger.InfoContext(context.Background(), "Stored", "ssn", "123-45-6789", "name", "Jane")
+
+		if strings.Contains(buf.String(), "123-45-6789") || !strings.Contains(buf.String(), "Jane") {
+			t.Errorf("Expected ssn to be redacted, got %s", buf.String())
+		}
+	})
+
+	t.Run("context attributes stay at the top level of groups", func(t *testing.T) {
+		buf.Reset()
+
+		ctx := navigaid.SetAuth(context.Background(), navigaid.AuthInfo{
+			Claims: navigaid.Claims{Org: "acme"},
+		}, nil)
+
+		logger := slog.New(dindenault.NewContextHandler(handler, dindenault.LogOptions{}))
+		logger.With("outer", 1).WithGroup("upstream").With("password", "hunter2").
+			WithGroup("response").InfoContext(ctx, "Fetched", "status", 200)
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("Invalid log record %q: %v", buf.String(), err)
+		}
+
+		if record["org"] != "acme" || record["outer"] != float64(1) {
+			t.Errorf("Expected org and outer at the top level, got %v", record)
+		}
+
+		upstream, _ := record["upstream"].(map[string]any)
+		response, _ := upstream["response"].(map[string]any)
+
+		if upstream["password"] != "[REDACTED]" || response["status"] != float64(200) || upstream["org"] != nil {
+			t.Errorf("Unexpected upstream group %v", upstream)
+		}
+	})
+
+	t.Run("redaction disabled", func(t *testing.T) {
+		buf.Reset()
+
+		logger := slog.New(dindenault.NewContextHandler(handler, dindenault.LogOptions{
+			DisableRedaction: true,
+		}))
+		logger.Info("Debugging", "password", "hunter2")
+
+		if !strings.Contains(buf.String(), "hunter2") {
+			t.Errorf("Expected password to be logged, got %s", buf.String())
+		}
+	})
+}
+
+func TestNewWithNilLogger(t *testing.T) {
+	app := dindenault.New(nil, dindenault.WithService("/api/", echoHandler()))
+
+	rec := httptest.NewRecorder()
+	app.HTTPHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", rec.Code)
+	}
+}
+
+func TestNewWrapsLoggerBeforeOptions(t *testing.T) {
+	var buf bytes.Buffer
+
+	app := dindenault.New(slog.New(slog.NewJSONHandler(&buf, nil)),
+		dindenault.WithPathPermissionService("/api/", readerJWKS(), http.NotFoundHandler(),
+			[]dindenault.PathPermissionConfig{
+				{PathPrefix: "/api/articles", Permissions: []string{"articles:write"}},
+			}),
+		// Applied after the service, but still used by its logger.
+		dindenault.WithLogOptions(dindenault.LogOptions{RedactKeys: []string{"user"}}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/articles/1", nil)
+	req.Header.Set("Authorization", "Bearer reader")
+	req.Header.Set("X-Request-Id", "options-request")
+
+	rec := httptest.NewRecorder()
+	app.HTTPHandler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", rec.Code)
+	}
+
+	var denied map[string]any
+
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("Invalid log line %q: %v", line, err)
+		}
+
+		if record["msg"] == "permission denied" {
+			denied = record
+		}
+	}
+
+	if denied["request_id"] != "options-request" || denied["user"] != "[REDACTED]" {
+		t.Errorf("Expected an enriched and redacted record, got %v", denied)
+	}
+}
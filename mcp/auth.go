@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"log/slog"
@@ -105,6 +106,7 @@ func AuthMiddleware(logger *slog.Logger, jwks *navigaid.JWKS, next http.Handler,
 		token, err := navigaid.GetAuthToken(r.Header)
 		if err != nil {
 			logger.Debug("mcp: missing authorization token", "error", err)
+			auditAuthentication(r.Context(), "no access token")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 			return
@@ -113,6 +115,7 @@ func AuthMiddleware(logger *slog.Logger, jwks *navigaid.JWKS, next http.Handler,
 		claims, err := jwks.Validate(token)
 		if err != nil {
 			logger.Debug("mcp: invalid token", "error", err)
+			auditAuthentication(r.Context(), "invalid token")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 			return
@@ -123,6 +126,25 @@ func AuthMiddleware(logger *slog.Logger, jwks *navigaid.JWKS, next http.Handler,
 			Claims:      claims,
 		}, nil)
 
+		auditAuthentication(ctx, "")
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// auditAuthentication records an authentication decision of
+// AuthMiddleware: a denial with the given reason, or an allow decision
+// if reason is empty.
+func auditAuthentication(ctx context.Context, reason string) {
+	decision := navigaid.AuditAllow
+	if reason != "" {
+		decision = navigaid.AuditDeny
+	}
+
+	navigaid.RecordAudit(ctx, navigaid.AuditEvent{
+		Type:     navigaid.AuditAuthentication,
+		Decision: decision,
+		Reason:   reason,
+		Source:   "mcp.AuthMiddleware",
+	})
+}
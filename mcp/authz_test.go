@@ -22,6 +22,14 @@ func permTestServer() *mcp.Server {
 				return json.RawMessage(`"secure ok"`), nil
 			},
 		},
+		mcp.Tool{
+			Name:        "editor_tool",
+			Description: "requires content:write or membership of editors",
+			Require:     navigaid.MustParseRequirement("content:write or group(editors)"),
+			Handler: func(_ context.Context, _ json.RawMessage) (json.RawMessage, error) {
+				return json.RawMessage(`"editor ok"`), nil
+			},
+		},
 		mcp.Tool{
 			Name:        "open_tool",
 			Description: "no permission requirement",
@@ -97,6 +105,78 @@ func TestRequiredPermissions(t *testing.T) {
 	})
 }
 
+func TestRequire(t *testing.T) {
+	server := permTestServer()
+
+	t.Run("allowed with permission", func(t *testing.T) {
+		resp := callTool(authContext([]string{"content:write"}), t, server, "editor_tool")
+		if resp["error"] != nil {
+			t.Fatalf("expected success, got error: %v", resp["error"])
+		}
+	})
+
+	t.Run("allowed with group", func(t *testing.T) {
+		ctx := navigaid.SetAuth(context.Background(), navigaid.AuthInfo{
+			Claims: navigaid.Claims{Org: "test-org", Groups: []string{"editors"}},
+		}, nil)
+
+		resp := callTool(ctx, t, server, "editor_tool")
+		if resp["error"] != nil {
+			t.Fatalf("expected success, got error: %v", resp["error"])
+		}
+	})
+
+	t.Run("denied with explanation", func(t *testing.T) {
+		resp := callTool(authContext([]string{"content:read"}), t, server, "editor_tool")
+
+		rpcErr, ok := resp["error"].(map[string]any)
+		if !ok {
+			t.Fatalf("expected permission denied error, got %v", resp)
+		}
+
+		message, _ := rpcErr["message"].(string)
+		if !strings.Contains(message, "not a member of group editors") {
+			t.Errorf("expected the denial to be explained, got %q", message)
+		}
+	})
+
+	t.Run("denied without auth in context", func(t *testing.T) {
+		resp := callTool(context.Background(), t, server, "editor_tool")
+		if resp["error"] == nil {
+			t.Fatal("a tool with Require must reject unauthenticated calls")
+		}
+	})
+}
+
+func TestToolAudit(t *testing.T) {
+	server := permTestServer()
+
+	var events []navigaid.AuditEvent
+
+	sink := navigaid.AuditSinkFunc(func(_ context.Context, event navigaid.AuditEvent) {
+		events = append(events, event)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/mcp", nil)
+
+	callTool(navigaid.ContextWithAudit(authContext([]string{"content:write"}), sink, req), t, server, "secure_tool")
+	callTool(navigaid.ContextWithAudit(authContext(nil), sink, req), t, server, "editor_tool")
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 audit events, got %+v", events)
+	}
+
+	if events[0].Decision != navigaid.AuditAllow || events[0].Procedure != "tools/call secure_tool" ||
+		events[0].Org != "test-org" {
+		t.Errorf("unexpected allow event %+v", events[0])
+	}
+
+	if events[1].Decision != navigaid.AuditDeny || events[1].Source != "mcp" ||
+		events[1].Reason != "lacks permission content:write and not a member of group editors" {
+		t.Errorf("unexpected deny event %+v", events[1])
+	}
+}
+
 func TestAuthorizationFromContextFallback(t *testing.T) {
 	t.Run("falls back to navigaid auth info", func(t *testing.T) {
 		ctx := authContext(nil)
@@ -9,7 +9,9 @@ import (
 
 // NewHTTPClient returns an *http.Client that forwards the MCP caller's
 // Authorization token on every outbound request. The token is read from ctx
-// via AuthorizationFromContext at call time and baked into the transport.
+// via AuthorizationFromContext at call time and baked into the transport,
+// as is the ID of the current request, which is sent in the X-Request-Id
+// header.
 //
 // Pass a shared base RoundTripper — e.g. http.DefaultTransport or a cached
 // *http.Transport — to preserve TCP connection pooling across calls. If base
@@ -21,6 +23,6 @@ import (
 //	client.Timeout = 15 * time.Second
 func NewHTTPClient(ctx context.Context, base http.RoundTripper) *http.Client {
 	return &http.Client{
-		Transport: httpforward.NewTransport(AuthorizationFromContext(ctx), base),
+		Transport: httpforward.NewTransport(ctx, AuthorizationFromContext(ctx), base),
 	}
 }
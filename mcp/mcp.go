@@ -44,8 +44,10 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/navigacontentlab/dindenault/navigaid"
 )
@@ -126,6 +128,12 @@ type Tool struct {
 	// Leave empty to handle authorization in the Handler itself.
 	RequiredPermissions []string
 
+	// Require, if set, is a requirement expression that the caller
+	// must also satisfy, such as
+	// navigaid.MustParseRequirement("articles:edit or group(admins)").
+	// Like RequiredPermissions it rejects unauthenticated calls.
+	Require navigaid.Requirement
+
 	// Handler is invoked when the tool is called.
 	Handler ToolHandler
 }
@@ -224,6 +232,52 @@ func (s *Server) handleToolsList(w http.ResponseWriter, req *jsonRPCRequest) {
 	writeResult(w, req.ID, toolsListResult{Tools: defs})
 }
 
+// authorizeTool checks the RequiredPermissions and Require of a tool
+// and records the decision. On denial it returns the error message.
+func authorizeTool(ctx context.Context, name string, tool *Tool) (string, bool) {
+	event := navigaid.AuditEvent{
+		Type:        navigaid.AuditAuthorization,
+		Decision:    navigaid.AuditDeny,
+		Source:      "mcp",
+		Procedure:   "tools/call " + name,
+		Permissions: tool.RequiredPermissions,
+	}
+
+	if tool.Require != nil {
+		event.Permissions = append(slices.Clip(event.Permissions), tool.Require.String())
+	}
+
+	authInfo, err := navigaid.GetAuth(ctx)
+	if err != nil {
+		event.Reason = "not authenticated"
+		navigaid.RecordAudit(ctx, event)
+
+		return fmt.Sprintf("Tool %q requires authentication", name), false
+	}
+
+	if !authInfo.Claims.HasPermissionsInOrganisation(tool.RequiredPermissions...) {
+		event.Reason = "lacks permissions"
+		navigaid.RecordAudit(ctx, event)
+
+		return fmt.Sprintf("Tool %q requires permissions: %v", name, tool.RequiredPermissions), false
+	}
+
+	var requirementErr *navigaid.RequirementError
+
+	if errors.As(navigaid.CheckRequirement(tool.Require, navigaid.Caller{Claims: authInfo.Claims}), &requirementErr) {
+		event.Reason = requirementErr.Reason
+		navigaid.RecordAudit(ctx, event)
+
+		return fmt.Sprintf("Tool %q: %v", name, requirementErr), false
+	}
+
+	event.Decision = navigaid.AuditAllow
+	event.Reason = "has required permissions"
+	navigaid.RecordAudit(ctx, event)
+
+	return "", true
+}
+
 func (s *Server) handleToolsCall(ctx context.Context, w http.ResponseWriter, req *jsonRPCRequest) {
 	var params toolsCallParams
 	if err := json.Unmarshal(req.Params, &params); err != nil {
@@ -239,18 +293,9 @@ func (s *Server) handleToolsCall(ctx context.Context, w http.ResponseWriter, req
 		return
 	}
 
-	if len(tool.RequiredPermissions) > 0 {
-		authInfo, err := navigaid.GetAuth(ctx)
-		if err != nil {
-			writeError(w, req.ID, codePermissionDenied,
-				fmt.Sprintf("Tool %q requires authentication", params.Name))
-
-			return
-		}
-
-		if !authInfo.Claims.HasPermissionsInOrganisation(tool.RequiredPermissions...) {
-			writeError(w, req.ID, codePermissionDenied,
-				fmt.Sprintf("Tool %q requires permissions: %v", params.Name, tool.RequiredPermissions))
+	if len(tool.RequiredPermissions) > 0 || tool.Require != nil {
+		if message, ok := authorizeTool(ctx, params.Name, tool); !ok {
+			writeError(w, req.ID, codePermissionDenied, message)
 
 			return
 		}
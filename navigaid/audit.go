@@ -0,0 +1,222 @@
+package navigaid
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/navigacontentlab/dindenault/internal/requestid"
+)
+
+// Audit event types.
+const (
+	AuditAuthentication = "authentication"
+	AuditAuthorization  = "authorization"
+)
+
+// Audit decisions.
+const (
+	AuditAllow = "allow"
+	AuditDeny  = "deny"
+)
+
+// AuditEvent describes an authentication or authorization decision.
+type AuditEvent struct {
+	Time time.Time `json:"time"`
+	// Type is AuditAuthentication or AuditAuthorization.
+	Type string `json:"type"`
+	// Decision is AuditAllow or AuditDeny.
+	Decision string `json:"decision"`
+	// Reason explains the decision, e.g. "invalid token" or "lacks
+	// permission articles:write".
+	Reason string `json:"reason,omitempty"`
+	// Source is the entry point that made the decision, e.g.
+	// "PathInterceptors" or "mcp".
+	Source string `json:"source"`
+
+	Subject     string   `json:"subject,omitempty"`
+	Org         string   `json:"org,omitempty"`
+	Procedure   string   `json:"procedure,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Unit        string   `json:"unit,omitempty"`
+	ClientIP    string   `json:"client_ip,omitempty"`
+	RequestID   string   `json:"request_id,omitempty"`
+}
+
+// AuditSink receives audit events. Implementations must be safe for
+// concurrent use, and should not block: events are recorded on the
+// request path.
+type AuditSink interface {
+	RecordAudit(ctx context.Context, event AuditEvent)
+}
+
+// AuditSinkFunc adapts a function to the AuditSink interface.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent)
+
+// RecordAudit calls f(ctx, event).
+func (f AuditSinkFunc) RecordAudit(ctx context.Context, event AuditEvent) {
+	f(ctx, event)
+}
+
+// auditScope is the audit sink and request metadata of a request.
+type auditScope struct {
+	sink     AuditSink
+	clientIP string
+	path     string
+}
+
+type auditContextKey struct{}
+
+// AuditMiddleware records the auth decisions made while serving
+// requests to sink. Events get the client IP from
+// http.Request.RemoteAddr, and the request path as procedure unless the
+// entry point sets one.
+//
+// dindenault.WithAuditSink applies it to every request of an App.
+func AuditMiddleware(sink AuditSink, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(ContextWithAudit(r.Context(), sink, r)))
+	})
+}
+
+// ContextWithAudit returns a context in which auth decisions are
+// recorded to sink, with the client IP and path of r.
+func ContextWithAudit(ctx context.Context, sink AuditSink, r *http.Request) context.Context {
+	clientIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+
+	return context.WithValue(ctx, auditContextKey{}, &auditScope{
+		sink:     sink,
+		clientIP: clientIP,
+		path:     r.URL.Path,
+	})
+}
+
+// RecordAudit records an event to the audit sink of ctx, if any. The
+// time, client IP, request ID and procedure are filled in if empty, and
+// the subject and organisation are taken from the authenticated caller.
+func RecordAudit(ctx context.Context, event AuditEvent) {
+	scope, ok := ctx.Value(auditContextKey{}).(*auditScope)
+	if !ok {
+		return
+	}
+
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	if event.ClientIP == "" {
+		event.ClientIP = scope.clientIP
+	}
+
+	if event.RequestID == "" {
+		event.RequestID = requestid.FromContext(ctx)
+	}
+
+	if event.Procedure == "" {
+		event.Procedure = scope.path
+	}
+
+	if auth, err := GetAuth(ctx); err == nil {
+		if event.Subject == "" {
+			event.Subject = auth.Claims.Subject
+		}
+
+		if event.Org == "" {
+			event.Org = auth.Claims.Org
+		}
+	}
+
+	scope.sink.RecordAudit(ctx, event)
+}
+
+// auditDecision returns AuditAllow or AuditDeny.
+func auditDecision(allowed bool) string {
+	if allowed {
+		return AuditAllow
+	}
+
+	return AuditDeny
+}
+
+// auditAuthentication records an authentication decision: a denial
+// with the given reason, or an allow decision if reason is empty.
+func auditAuthentication(ctx context.Context, source, procedure, reason string) {
+	RecordAudit(ctx, AuditEvent{
+		Type:      AuditAuthentication,
+		Decision:  auditDecision(reason == ""),
+		Reason:    reason,
+		Source:    source,
+		Procedure: procedure,
+	})
+}
+
+// permissionReason explains the decision of a permission check.
+func permissionReason(allowed bool, permission string) string {
+	if allowed {
+		return "has permission " + permission
+	}
+
+	return "lacks permission " + permission
+}
+
+// NewSlogAuditSink returns an AuditSink that logs events as "auth
+// decision" records at info level.
+//
+//nolint:ireturn // Returning interface as intended by AuditSink design
+func NewSlogAuditSink(logger *slog.Logger) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) {
+		logger.LogAttrs(ctx, slog.LevelInfo, "auth decision",
+			slog.String("audit_type", event.Type),
+			slog.String("decision", event.Decision),
+			slog.String("reason", event.Reason),
+			slog.String("source", event.Source),
+			slog.String("subject", event.Subject),
+			slog.String("org", event.Org),
+			slog.String("procedure", event.Procedure),
+			slog.Any("permissions", event.Permissions),
+			slog.String("unit", event.Unit),
+			slog.String("client_ip", event.ClientIP),
+			slog.String("request_id", event.RequestID),
+		)
+	})
+}
+
+// NewJSONLinesAuditSink returns an AuditSink that writes each event as
+// a line of JSON to w. Write errors are dropped.
+//
+//nolint:ireturn // Returning interface as intended by AuditSink design
+func NewJSONLinesAuditSink(w io.Writer) AuditSink {
+	var mu sync.Mutex
+
+	encoder := json.NewEncoder(w)
+
+	return AuditSinkFunc(func(_ context.Context, event AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		_ = encoder.Encode(event)
+	})
+}
+
+// SampleAllowed returns an AuditSink that forwards every deny decision
+// to sink, but only the given fraction (0 to 1) of allow decisions.
+//
+//nolint:ireturn // Returning interface as intended by AuditSink design
+func SampleAllowed(sink AuditSink, rate float64) AuditSink {
+	return AuditSinkFunc(func(ctx context.Context, event AuditEvent) {
+		if event.Decision == AuditAllow && rand.Float64() >= rate { //nolint:gosec // sampling needs no secure randomness
+			return
+		}
+
+		sink.RecordAudit(ctx, event)
+	})
+}
@@ -0,0 +1,149 @@
+package navigaid_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// auditRecorder collects audit events.
+type auditRecorder struct {
+	mu     sync.Mutex
+	events []navigaid.AuditEvent
+}
+
+func (r *auditRecorder) RecordAudit(_ context.Context, event navigaid.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events = append(r.events, event)
+}
+
+func TestAuditMiddleware(t *testing.T) {
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(string) (navigaid.Claims, error) {
+		claims := navigaid.Claims{Org: "acme"}
+		claims.Subject = "user-1"
+		claims.Permissions.Org = []string{"articles:read"}
+
+		return claims, nil
+	})
+
+	recorder := &auditRecorder{}
+
+	handler := navigaid.AuditMiddleware(recorder, navigaid.HTTPMiddleware(slog.Default(), jwks,
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = navigaid.CheckPermissionConnect(r.Context(), slog.Default(), "articles:read")
+			_ = navigaid.CheckUnitPermissionConnect(r.Context(), slog.Default(), "unit-a", "articles:write")
+
+			w.WriteHeader(http.StatusNoContent)
+		})))
+
+	req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+	req.RemoteAddr = "192.0.2.1:1234"
+	req.Header.Set("Authorization", "Bearer token")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodGet, "/articles", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := []struct {
+		typ, decision, source, reason string
+	}{
+		{navigaid.AuditAuthentication, navigaid.AuditAllow, "HTTPMiddleware", ""},
+		{navigaid.AuditAuthorization, navigaid.AuditAllow, "CheckPermissionConnect", "has permission articles:read"},
+		{navigaid.AuditAuthorization, navigaid.AuditDeny, "CheckUnitPermissionConnect", "lacks permission articles:write"},
+		{navigaid.AuditAuthentication, navigaid.AuditDeny, "HTTPMiddleware", "no access token"},
+	}
+
+	if len(recorder.events) != len(expected) {
+		t.Fatalf("Expected %d events, got %+v", len(expected), recorder.events)
+	}
+
+	for i, want := range expected {
+		event := recorder.events[i]
+		if event.Type != want.typ || event.Decision != want.decision ||
+			event.Source != want.source || event.Reason != want.reason {
+			t.Errorf("Event %d: expected %+v, got %+v", i, want, event)
+		}
+
+		if event.Procedure != "/articles" || event.Time.IsZero() {
+			t.Errorf("Event %d: missing request metadata: %+v", i, event)
+		}
+	}
+
+	first := recorder.events[0]
+	if first.Subject != "user-1" || first.Org != "acme" || first.ClientIP != "192.0.2.1" {
+		t.Errorf("Expected the caller and client IP, got %+v", first)
+	}
+
+	if recorder.events[2].Unit != "unit-a" {
+		t.Errorf("Expected the unit, got %+v", recorder.events[2])
+	}
+}
+
+func TestRecordAuditWithoutSink(t *testing.T) {
+	// Decisions made outside AuditMiddleware are not recorded.
+	navigaid.RecordAudit(context.Background(), navigaid.AuditEvent{Type: navigaid.AuditAuthorization})
+}
+
+func TestJSONLinesAuditSink(t *testing.T) {
+	var buf bytes.Buffer
+
+	sink := navigaid.NewJSONLinesAuditSink(&buf)
+
+	req := httptest.NewRequest(http.MethodGet, "/articles", nil)
+	ctx := navigaid.ContextWithAudit(context.Background(), sink, req)
+
+	navigaid.RecordAudit(ctx, navigaid.AuditEvent{
+		Type:        navigaid.AuditAuthorization,
+		Decision:    navigaid.AuditDeny,
+		Source:      "test",
+		Permissions: []string{"articles:write"},
+	})
+	navigaid.RecordAudit(ctx, navigaid.AuditEvent{
+		Type:     navigaid.AuditAuthentication,
+		Decision: navigaid.AuditAllow,
+		Source:   "test",
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 lines, got %q", buf.String())
+	}
+
+	var event map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &event); err != nil {
+		t.Fatalf("Invalid JSON line: %v", err)
+	}
+
+	if event["decision"] != "deny" || event["procedure"] != "/articles" || event["client_ip"] != "192.0.2.1" {
+		t.Errorf("Unexpected event %v", event)
+	}
+}
+
+func TestSampleAllowed(t *testing.T) {
+	for _, rate := range []float64{0, 1} {
+		recorder := &auditRecorder{}
+		sink := navigaid.SampleAllowed(recorder, rate)
+
+		for range 10 {
+			sink.RecordAudit(context.Background(), navigaid.AuditEvent{Decision: navigaid.AuditAllow})
+			sink.RecordAudit(context.Background(), navigaid.AuditEvent{Decision: navigaid.AuditDeny})
+		}
+
+		expected := 10 + int(rate*10)
+		if len(recorder.events) != expected {
+			t.Errorf("Rate %v: expected %d events, got %d", rate, expected, len(recorder.events))
+		}
+	}
+}
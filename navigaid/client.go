@@ -0,0 +1,189 @@
+package navigaid
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"connectrpc.com/connect"
+
+	"github.com/navigacontentlab/dindenault/internal/requestid"
+)
+
+// TokenSource provides the access token for outgoing calls that are not
+// made on behalf of an authenticated caller.
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// TokenSourceFunc adapts a function to a TokenSource.
+type TokenSourceFunc func(ctx context.Context) (string, error)
+
+// Token implements TokenSource.
+func (f TokenSourceFunc) Token(ctx context.Context) (string, error) {
+	return f(ctx)
+}
+
+// TokenSource returns a TokenSource that exchanges navigaIDToken, the
+// service's own Naviga ID token, for access tokens. Tokens are cached by
+// the refresher until shortly before they expire.
+//
+//nolint:ireturn
+func (tr *TokenRefresher) TokenSource(navigaIDToken string) TokenSource {
+	return TokenSourceFunc(func(ctx context.Context) (string, error) {
+		return tr.GetAccessToken(ctx, navigaIDToken)
+	})
+}
+
+// TokenSource returns a TokenSource that exchanges navigaIDToken, the
+// service's own Naviga ID token, for access tokens, and caches them
+// until shortly before they expire. It is a shorthand for the
+// TokenSource of NewServiceTokenRefresher(logger, ats).
+//
+//nolint:ireturn
+func (ats *AccessTokenService) TokenSource(logger *slog.Logger, navigaIDToken string) TokenSource {
+	return NewServiceTokenRefresher(logger, ats).TokenSource(navigaIDToken)
+}
+
+// ClientInterceptorOption configures ClientInterceptor.
+type ClientInterceptorOption func(c *clientInterceptor)
+
+// WithServiceToken makes ClientInterceptor authenticate calls made
+// without an authenticated caller in the context with a token from
+// source, typically the TokenSource of a TokenRefresher.
+func WithServiceToken(source TokenSource) ClientInterceptorOption {
+	return func(c *clientInterceptor) {
+		c.serviceToken = source
+	}
+}
+
+// ClientInterceptor returns an interceptor for Connect clients that
+// authenticates outgoing calls, unary and streaming alike. Each call
+// forwards the caller's access token from GetAuth(ctx), so a service
+// calls downstream services on behalf of the user that called it. Calls
+// made without auth info in the context use the service token configured
+// with WithServiceToken, or are sent unauthenticated. An Authorization
+// header set on the request is left as is.
+//
+// The ID of the current request is forwarded in the X-Request-Id header.
+//
+// Example:
+//
+//	refresher := navigaid.NewTokenRefresher(logger, navigaid.AccessTokenEndpoint(imasURL))
+//
+//	client := articlev1connect.NewArticleServiceClient(
+//	    http.DefaultClient,
+//	    articleServiceURL,
+//	    connect.WithInterceptors(navigaid.ClientInterceptor(logger,
+//	        navigaid.WithServiceToken(refresher.TokenSource(serviceNavigaIDToken)),
+//	    )),
+//	)
+//
+//nolint:ireturn
+func ClientInterceptor(logger *slog.Logger, options ...ClientInterceptorOption) connect.Interceptor {
+	logger.Debug("Creating Connect client interceptor for authentication")
+
+	c := &clientInterceptor{logger: logger}
+
+	for _, o := range options {
+		o(c)
+	}
+
+	return c
+}
+
+type clientInterceptor struct {
+	logger       *slog.Logger
+	serviceToken TokenSource
+}
+
+func (c *clientInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			if err := c.authorize(ctx, req.Header()); err != nil {
+				return nil, err
+			}
+		}
+
+		return next(ctx, req)
+	}
+}
+
+func (c *clientInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+
+		if err := c.authorize(ctx, conn.RequestHeader()); err != nil {
+			return &failedClientConn{StreamingClientConn: conn, err: err}
+		}
+
+		return conn
+	}
+}
+
+func (c *clientInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// authorize sets the Authorization and X-Request-Id headers of an
+// outgoing call.
+func (c *clientInterceptor) authorize(ctx context.Context, header http.Header) error {
+	if header.Get(requestid.Header) == "" {
+		if id := requestid.FromContext(ctx); id != "" {
+			header.Set(requestid.Header, id)
+		}
+	}
+
+	if header.Get("Authorization") != "" {
+		return nil
+	}
+
+	if auth, err := GetAuth(ctx); err == nil && auth.AccessToken != "" {
+		header.Set("Authorization", "Bearer "+auth.AccessToken)
+
+		return nil
+	}
+
+	if c.serviceToken == nil {
+		c.logger.Debug("no caller or service token for outgoing call")
+
+		return nil
+	}
+
+	token, err := c.serviceToken.Token(ctx)
+	if err != nil {
+		c.logger.Error("failed to obtain service token", "error", err)
+
+		return connect.NewError(connect.CodeUnauthenticated,
+			fmt.Errorf("failed to obtain service token: %w", err))
+	}
+
+	header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+// failedClientConn fails a stream that could not be authorized, without
+// sending anything.
+type failedClientConn struct {
+	connect.StreamingClientConn
+
+	err error
+}
+
+func (c *failedClientConn) Send(any) error {
+	return c.err
+}
+
+func (c *failedClientConn) Receive(any) error {
+	return c.err
+}
+
+func (c *failedClientConn) CloseRequest() error {
+	return nil
+}
+
+func (c *failedClientConn) CloseResponse() error {
+	return nil
+}
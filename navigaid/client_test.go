@@ -0,0 +1,158 @@
+package navigaid_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// newAuthEchoServer serves a unary and a server streaming procedure that
+// record the Authorization header they receive.
+func newAuthEchoServer(t *testing.T) (*httptest.Server, *string) {
+	t.Helper()
+
+	var received string
+
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.Service/Get", connect.NewUnaryHandler("/test.v1.Service/Get",
+		func(_ context.Context, req *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			received = req.Header().Get("Authorization")
+
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}))
+	mux.Handle("/test.v1.Service/Watch", connect.NewServerStreamHandler("/test.v1.Service/Watch",
+		func(_ context.Context, req *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			received = req.Header().Get("Authorization")
+
+			return stream.Send(&emptypb.Empty{})
+		}))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server, &received
+}
+
+func TestClientInterceptor(t *testing.T) {
+	server, received := newAuthEchoServer(t)
+
+	serviceToken := navigaid.TokenSourceFunc(func(context.Context) (string, error) {
+		return "service-token", nil
+	})
+
+	interceptor := navigaid.ClientInterceptor(slog.Default(), navigaid.WithServiceToken(serviceToken))
+
+	unary := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+		server.URL+"/test.v1.Service/Get", connect.WithInterceptors(interceptor))
+	streaming := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+		server.URL+"/test.v1.Service/Watch", connect.WithInterceptors(interceptor))
+
+	userCtx := navigaid.SetAuth(context.Background(), navigaid.AuthInfo{AccessToken: "user-token"}, nil)
+
+	callStream := func(ctx context.Context) error {
+		stream, err := streaming.CallServerStream(ctx, connect.NewRequest(&emptypb.Empty{}))
+		if err != nil {
+			return err //nolint:wrapcheck // test helper
+		}
+
+		defer func() { _ = stream.Close() }()
+
+		for stream.Receive() {
+		}
+
+		return stream.Err() //nolint:wrapcheck // test helper
+	}
+
+	t.Run("unary forwards the caller's token", func(t *testing.T) {
+		_, err := unary.CallUnary(userCtx, connect.NewRequest(&emptypb.Empty{}))
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer user-token", *received)
+	})
+
+	t.Run("unary falls back to the service token", func(t *testing.T) {
+		_, err := unary.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer service-token", *received)
+	})
+
+	t.Run("unary keeps an explicit Authorization header", func(t *testing.T) {
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set("Authorization", "Bearer explicit")
+
+		_, err := unary.CallUnary(userCtx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer explicit", *received)
+	})
+
+	t.Run("streaming forwards the caller's token", func(t *testing.T) {
+		require.NoError(t, callStream(userCtx))
+		assert.Equal(t, "Bearer user-token", *received)
+	})
+
+	t.Run("streaming falls back to the service token", func(t *testing.T) {
+		require.NoError(t, callStream(context.Background()))
+		assert.Equal(t, "Bearer service-token", *received)
+	})
+}
+
+func TestClientInterceptor_ServiceTokenFailure(t *testing.T) {
+	server, received := newAuthEchoServer(t)
+
+	*received = "not called"
+
+	interceptor := navigaid.ClientInterceptor(slog.Default(), navigaid.WithServiceToken(
+		navigaid.TokenSourceFunc(func(context.Context) (string, error) {
+			return "", errors.New("token endpoint unavailable")
+		})))
+
+	unary := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+		server.URL+"/test.v1.Service/Get", connect.WithInterceptors(interceptor))
+
+	_, err := unary.CallUnary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+
+	streaming := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+		server.URL+"/test.v1.Service/Watch", connect.WithInterceptors(interceptor))
+
+	stream, err := streaming.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	if err == nil {
+		assert.False(t, stream.Receive())
+		err = stream.Err()
+	}
+
+	assert.Equal(t, connect.CodeUnauthenticated, connect.CodeOf(err))
+	assert.Equal(t, "not called", *received, "no request may be sent without a token")
+}
+
+func TestAccessTokenService_TokenSource(t *testing.T) {
+	var exchanges int
+
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+
+		assert.Equal(t, "Bearer service-navigaid-token", r.Header.Get("Authorization"))
+
+		_, _ = w.Write([]byte(`{"access_token":"access","token_type":"Bearer","expires_in":3600}`))
+	}))
+	defer tokenServer.Close()
+
+	source := navigaid.New(tokenServer.URL).TokenSource(slog.Default(), "service-navigaid-token")
+
+	for range 2 {
+		token, err := source.Token(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "access", token)
+	}
+
+	assert.Equal(t, 1, exchanges, "tokens should be cached")
+}
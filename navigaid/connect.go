@@ -4,59 +4,64 @@ import (
 	"context"
 	"errors"
 	"log/slog"
+	"net/http"
 	"strings"
 
 	"connectrpc.com/connect"
+
+	"github.com/navigacontentlab/dindenault/internal/interceptors"
 )
 
 // ConnectInterceptor returns an interceptor for Connect RPC
-// that adds authentication to requests.
+// that adds authentication to requests. It authenticates unary calls
+// and streaming handlers alike, before the handler runs.
 //
 //nolint:ireturn
 func ConnectInterceptor(logger *slog.Logger, jwks *JWKS) connect.Interceptor {
 	logger.Debug("Creating Connect interceptor for authentication")
 
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Try to extract token from multiple possible headers
-			accessToken := extractAccessToken(req)
+	return interceptors.Handler(func(ctx context.Context, spec connect.Spec, header http.Header) (context.Context, error) {
+		// Try to extract token from multiple possible headers
+		accessToken := extractAccessToken(header)
 
-			if accessToken == "" {
-				logger.Info("no access token in request")
+		if accessToken == "" {
+			logger.Info("no access token in request")
+			auditAuthentication(ctx, "ConnectInterceptor", spec.Procedure, "no access token")
 
-				return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
-			}
+			return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
+		}
 
-			// Validate the token
-			claims, err := jwks.Validate(accessToken)
-			if err != nil {
-				logger.Error("token validation failed", "error", err)
+		// Validate the token
+		claims, err := jwks.Validate(accessToken)
+		if err != nil {
+			logger.Error("token validation failed", "error", err)
+			auditAuthentication(ctx, "ConnectInterceptor", spec.Procedure, "invalid token")
 
-				return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid token"))
-			}
+			return ctx, connect.NewError(connect.CodeUnauthenticated, errors.New("invalid token"))
+		}
 
-			// Set auth info in context
-			newCtx := SetAuth(ctx, AuthInfo{
-				AccessToken: accessToken,
-				Claims:      claims,
-			}, nil)
+		// Continue with the auth info in the context
+		ctx = SetAuth(ctx, AuthInfo{
+			AccessToken: accessToken,
+			Claims:      claims,
+		}, nil)
 
-			// Call the next handler with the authenticated context
-			return next(newCtx, req)
-		}
+		auditAuthentication(ctx, "ConnectInterceptor", spec.Procedure, "")
+
+		return ctx, nil
 	})
 }
 
 // extractAccessToken tries to extract the access token from various headers
 // to maintain compatibility with panurge.
-func extractAccessToken(req connect.AnyRequest) string {
+func extractAccessToken(header http.Header) string {
 	// First try Authorization header (standard)
-	authHeader := req.Header().Get("Authorization")
+	authHeader := header.Get("Authorization")
 	if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 		return strings.TrimPrefix(authHeader, "Bearer ")
 	}
 
-	imidToken := req.Header().Get("x-imid-token")
+	imidToken := header.Get("x-imid-token")
 	if imidToken != "" {
 		return imidToken
 	}
@@ -65,28 +70,27 @@ func extractAccessToken(req connect.AnyRequest) string {
 }
 
 // RequirePermission returns an interceptor that checks
-// if the user has the specified permission.
+// if the user has the specified permission, for unary calls and
+// streaming handlers.
 //
 //nolint:ireturn
 func RequirePermission(logger *slog.Logger, permission string) connect.Interceptor {
 	logger.Debug("Creating Connect interceptor for permission", "permission", permission)
 
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Check if the user has the required permission
-			if err := CheckPermissionConnect(ctx, logger, permission); err != nil {
-				return nil, connect.NewError(connect.CodePermissionDenied,
-					errors.New("missing required permission: "+permission))
-			}
-
-			// Call the next handler
-			return next(ctx, req)
+	return interceptors.Handler(func(ctx context.Context, _ connect.Spec, _ http.Header) (context.Context, error) {
+		// Check if the user has the required permission
+		if err := CheckPermissionConnect(ctx, logger, permission); err != nil {
+			return ctx, connect.NewError(connect.CodePermissionDenied,
+				errors.New("missing required permission: "+permission))
 		}
+
+		return ctx, nil
 	})
 }
 
 // RequireUnitPermission returns an interceptor that checks
-// if the user has the specified permission for a unit.
+// if the user has the specified permission for a unit, for unary calls
+// and streaming handlers.
 //
 //nolint:ireturn
 func RequireUnitPermission(logger *slog.Logger, unit string, permission string) connect.Interceptor {
@@ -94,17 +98,14 @@ func RequireUnitPermission(logger *slog.Logger, unit string, permission string)
 		"unit", unit,
 		"permission", permission)
 
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Check if the user has the required permission for the unit
-			if err := CheckUnitPermissionConnect(ctx, logger, unit, permission); err != nil {
-				return nil, connect.NewError(connect.CodePermissionDenied,
-					errors.New("missing required permission for unit: "+unit+"/"+permission))
-			}
-
-			// Call the next handler
-			return next(ctx, req)
+	return interceptors.Handler(func(ctx context.Context, _ connect.Spec, _ http.Header) (context.Context, error) {
+		// Check if the user has the required permission for the unit
+		if err := CheckUnitPermissionConnect(ctx, logger, unit, permission); err != nil {
+			return ctx, connect.NewError(connect.CodePermissionDenied,
+				errors.New("missing required permission for unit: "+unit+"/"+permission))
 		}
+
+		return ctx, nil
 	})
 }
 
@@ -154,12 +155,29 @@ func CheckPermissionConnect(ctx context.Context, logger *slog.Logger, permission
 	authInfo, err := GetAuth(ctx)
 	if err != nil {
 		logger.Info("authentication required", "error", err)
+		RecordAudit(ctx, AuditEvent{
+			Type:        AuditAuthorization,
+			Decision:    AuditDeny,
+			Reason:      "not authenticated",
+			Source:      "CheckPermissionConnect",
+			Permissions: []string{permission},
+		})
 
 		return errors.New("authentication required")
 	}
 
 	// Check if the user has the required permission
-	if !authInfo.Claims.HasPermissionsInOrganisation(permission) {
+	allowed := authInfo.Claims.HasPermissionsInOrganisation(permission)
+
+	RecordAudit(ctx, AuditEvent{
+		Type:        AuditAuthorization,
+		Decision:    auditDecision(allowed),
+		Reason:      permissionReason(allowed, permission),
+		Source:      "CheckPermissionConnect",
+		Permissions: []string{permission},
+	})
+
+	if !allowed {
 		logger.Info("permission denied",
 			"permission", permission,
 			"user", authInfo.Claims.Subject,
@@ -177,12 +195,31 @@ func CheckUnitPermissionConnect(ctx context.Context, logger *slog.Logger, unit,
 	authInfo, err := GetAuth(ctx)
 	if err != nil {
 		logger.Info("authentication required", "error", err)
+		RecordAudit(ctx, AuditEvent{
+			Type:        AuditAuthorization,
+			Decision:    AuditDeny,
+			Reason:      "not authenticated",
+			Source:      "CheckUnitPermissionConnect",
+			Permissions: []string{permission},
+			Unit:        unit,
+		})
 
 		return errors.New("authentication required")
 	}
 
 	// Check if the user has the required permission in the specified unit
-	if !authInfo.Claims.HasPermissionsInUnit(unit, permission) {
+	allowed := authInfo.Claims.HasPermissionsInUnit(unit, permission)
+
+	RecordAudit(ctx, AuditEvent{
+		Type:        AuditAuthorization,
+		Decision:    auditDecision(allowed),
+		Reason:      permissionReason(allowed, permission),
+		Source:      "CheckUnitPermissionConnect",
+		Permissions: []string{permission},
+		Unit:        unit,
+	})
+
+	if !allowed {
 		logger.Info("permission denied for unit",
 			"unit", unit,
 			"permission", permission,
@@ -21,6 +21,7 @@ func HTTPMiddleware(logger *slog.Logger, jwks *JWKS, next http.Handler) http.Han
 		token, err := GetAuthToken(r.Header)
 		if err != nil {
 			logger.Debug("missing authorization token", "error", err)
+			auditAuthentication(r.Context(), "HTTPMiddleware", "", "no access token")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 			return
@@ -29,6 +30,7 @@ func HTTPMiddleware(logger *slog.Logger, jwks *JWKS, next http.Handler) http.Han
 		claims, err := jwks.Validate(token)
 		if err != nil {
 			logger.Debug("invalid token", "error", err)
+			auditAuthentication(r.Context(), "HTTPMiddleware", "", "invalid token")
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 
 			return
@@ -39,6 +41,8 @@ func HTTPMiddleware(logger *slog.Logger, jwks *JWKS, next http.Handler) http.Han
 			Claims:      claims,
 		}, nil)
 
+		auditAuthentication(ctx, "HTTPMiddleware", "", "")
+
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -46,7 +50,8 @@ func HTTPMiddleware(logger *slog.Logger, jwks *JWKS, next http.Handler) http.Han
 // NewHTTPClient returns an *http.Client that forwards the authenticated
 // caller's token on every outbound request. The token is read from ctx via
 // GetAuth at call time; if no auth info is present the client makes
-// unauthenticated requests.
+// unauthenticated requests. The ID of the current request is forwarded
+// in the X-Request-Id header.
 //
 // Pass a shared base RoundTripper (e.g. http.DefaultTransport or a cached
 // *http.Transport) to preserve TCP connection pooling across calls. If base
@@ -68,6 +73,6 @@ func NewHTTPClient(ctx context.Context, base http.RoundTripper) *http.Client {
 	}
 
 	return &http.Client{
-		Transport: httpforward.NewTransport(token, base),
+		Transport: httpforward.NewTransport(ctx, token, base),
 	}
 }
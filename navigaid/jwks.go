@@ -2,14 +2,22 @@ package navigaid
 
 import (
 	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"math/big"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -17,6 +25,10 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// validMethods are the token signing algorithms accepted by
+// ValidateToken.
+var validMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "EdDSA"}
+
 const (
 	defaultJwksTTL = 10 * time.Minute
 
@@ -42,6 +54,9 @@ type JWKS struct {
 	ttl              time.Duration
 	expectedIssuer   string
 	expectedAudience string
+	x5cRoots         *x509.CertPool
+	inferAlgorithms  bool
+	logger           *slog.Logger
 
 	m              sync.Mutex
 	jwksStaleAfter time.Time
@@ -85,6 +100,35 @@ func WithExpectedAudience(audience string) JWKSOption {
 	}
 }
 
+// WithX5CRoots makes the JWKS accept only keys with an "x5c"
+// certificate chain that verifies against roots. Without it, chains are
+// optional, but the first certificate of a chain must still hold the
+// key.
+func WithX5CRoots(roots *x509.CertPool) JWKSOption {
+	return func(j *JWKS) {
+		j.x5cRoots = roots
+	}
+}
+
+// WithInferredKeyAlgorithms accepts keys that do not declare an "alg",
+// and lets them verify tokens signed with any algorithm that fits the
+// key type and curve. By default such keys are rejected when the JWKS
+// is fetched, since tokens must match the algorithm declared for the
+// key.
+func WithInferredKeyAlgorithms() JWKSOption {
+	return func(j *JWKS) {
+		j.inferAlgorithms = true
+	}
+}
+
+// WithJwksLogger sets the logger for failed refreshes and rejected
+// keys. It defaults to slog.Default.
+func WithJwksLogger(logger *slog.Logger) JWKSOption {
+	return func(j *JWKS) {
+		j.logger = logger
+	}
+}
+
 // SetValidationFunc sets a custom validation function for testing.
 func (j *JWKS) SetValidationFunc(fn ValidateFunc) {
 	j.validate = fn
@@ -105,11 +149,37 @@ func NewJWKS(jwksEndpoint string, options ...JWKSOption) *JWKS {
 		j.client = &http.Client{Timeout: defaultHTTPTimeout}
 	}
 
+	if j.logger == nil {
+		j.logger = slog.Default()
+	}
+
 	return &j
 }
 
-func (j *JWKS) fetchJWKS() (*jwksResponse, error) {
-	req, err := http.NewRequest(http.MethodGet, j.jwksEndpoint, nil)
+// Ping fetches the JWKS to verify that the endpoint is reachable and
+// publishes at least one usable key. On success the fetched keys
+// replace the cached ones.
+func (j *JWKS) Ping(ctx context.Context) error {
+	res, err := j.fetchJWKS(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	if err := res.usable(); err != nil {
+		return err
+	}
+
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	j.jwks = res
+	j.jwksStaleAfter = time.Now().Add(j.ttl)
+
+	return nil
+}
+
+func (j *JWKS) fetchJWKS(ctx context.Context) (*jwksResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, j.jwksEndpoint, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create jwks fetch request: %w", err)
 	}
@@ -136,36 +206,76 @@ func (j *JWKS) fetchJWKS() (*jwksResponse, error) {
 		return nil, fmt.Errorf("failed to decode JWKS response: %w", err)
 	}
 
+	jwks.parseKeys(j.x5cRoots, j.inferAlgorithms)
+
 	return &jwks, nil
 }
 
+// Ready reports whether the JWKS holds usable keys. Unlike Ping it
+// only fetches the JWKS when the cached keys are stale, and it keeps
+// reporting ready while stale keys are served during a JWKS outage, so
+// it is cheap enough to call from readiness probes.
+func (j *JWKS) Ready(ctx context.Context) error {
+	j.m.Lock()
+	defer j.m.Unlock()
+
+	return j.refresh(ctx)
+}
+
+// refresh fetches the JWKS if the cached keys are stale. A JWKS
+// without usable keys counts as a failed fetch. It must be called with
+// j.m held.
+func (j *JWKS) refresh(ctx context.Context) error {
+	if !time.Now().After(j.jwksStaleAfter) {
+		return nil
+	}
+
+	res, err := j.fetchJWKS(ctx)
+	if err == nil {
+		if len(res.rejected) > 0 {
+			j.logger.WarnContext(ctx, "Rejected JWKS keys",
+				"endpoint", j.jwksEndpoint,
+				"rejected", res.rejected)
+		}
+
+		err = res.usable()
+	} else {
+		err = fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	switch {
+	case err == nil:
+		j.jwks = res
+		j.jwksStaleAfter = time.Now().Add(j.ttl)
+	case j.jwks != nil:
+		// Refresh failed but we have previously fetched keys: keep
+		// serving them and back off before retrying, instead of
+		// failing all authentication on a transient JWKS outage.
+		j.logger.WarnContext(ctx, "Failed to refresh JWKS, using cached keys",
+			"endpoint", j.jwksEndpoint,
+			"error", err)
+
+		j.jwksStaleAfter = time.Now().Add(jwksRetryBackoff)
+	default:
+		return err
+	}
+
+	return nil
+}
+
 func (j *JWKS) getKey(kid string) (*jwksKey, error) {
 	j.m.Lock()
 	defer j.m.Unlock()
 
 	// ensure up-to-date version of our jwks
-	if time.Now().After(j.jwksStaleAfter) {
-		res, err := j.fetchJWKS()
-
-		switch {
-		case err == nil:
-			j.jwks = res
-			j.jwksStaleAfter = time.Now().Add(j.ttl)
-		case j.jwks != nil:
-			// Refresh failed but we have previously fetched keys: keep
-			// serving them and back off before retrying, instead of
-			// failing all authentication on a transient JWKS outage.
-			j.jwksStaleAfter = time.Now().Add(jwksRetryBackoff)
-		default:
-			return nil, fmt.Errorf(
-				"failed to fetch jwks: %w", err)
-		}
+	if err := j.refresh(context.Background()); err != nil {
+		return nil, err
 	}
 
 	// find the correct key
-	for _, key := range j.jwks.Keys {
-		if key.Kid == kid {
-			return &key, nil
+	for i := range j.jwks.Keys {
+		if j.jwks.Keys[i].Kid == kid {
+			return &j.jwks.Keys[i], nil
 		}
 	}
 
@@ -189,15 +299,19 @@ func (j *JWKS) Validate(accessToken string) (Claims, error) {
 // it and then looking up the "kid" to match with a jwk (which are
 // cached locally).
 //
-// Validation requires an RSA signature from a known key, an expiration
-// time ("exp"), and a matching token type. If the JWKS was configured
-// with WithExpectedIssuer or WithExpectedAudience those claims are
-// verified as well.
+// Validation requires a signature from a known key, an expiration time
+// ("exp"), and a matching token type. RSA (RS256, RS384, RS512), EC
+// (ES256 on P-256, ES384 on P-384) and Ed25519 (EdDSA) keys are
+// supported; the token algorithm must match the "alg" declared for the
+// key and fit the key (keys without "alg" are only accepted with
+// WithInferredKeyAlgorithms). If the JWKS was configured with
+// WithExpectedIssuer or WithExpectedAudience those claims are verified
+// as well.
 func (j *JWKS) ValidateToken(token string, tokenType string) (Claims, error) {
 	var claims Claims
 
 	parserOpts := []jwt.ParserOption{
-		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512"}),
+		jwt.WithValidMethods(validMethods),
 		jwt.WithExpirationRequired(),
 	}
 
@@ -210,10 +324,6 @@ func (j *JWKS) ValidateToken(token string, tokenType string) (Claims, error) {
 	}
 
 	t, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-
 		kid, ok := token.Header["kid"].(string)
 		if !ok || kid == "" {
 			return nil, errors.New("token has no kid header")
@@ -224,12 +334,12 @@ func (j *JWKS) ValidateToken(token string, tokenType string) (Claims, error) {
 			return nil, errors.New("unknown key id")
 		}
 
-		// ensure we have the same algorithm
-		if token.Method.Alg() != jwk.Alg {
-			return nil, errors.New("algorithm is not the same")
+		// ensure the algorithm fits the key
+		if !jwk.supports(token.Method.Alg()) {
+			return nil, errors.New("algorithm does not match the key")
 		}
 
-		return jwk.publicKey()
+		return jwk.key, nil
 	}, parserOpts...)
 	if err != nil {
 		return Claims{}, fmt.Errorf("failed to parse token: %w", err)
@@ -247,43 +357,249 @@ func (j *JWKS) ValidateToken(token string, tokenType string) (Claims, error) {
 }
 
 type jwksKey struct {
-	Kty string `json:"kty"`
-	Use string `json:"use"`
-	Alg string `json:"alg"`
-	Kid string `json:"kid"`
-	N   string `json:"n"`
-	E   string `json:"e"`
+	Kty string   `json:"kty"`
+	Use string   `json:"use"`
+	Alg string   `json:"alg"`
+	Kid string   `json:"kid"`
+	N   string   `json:"n"`
+	E   string   `json:"e"`
+	Crv string   `json:"crv"`
+	X   string   `json:"x"`
+	Y   string   `json:"y"`
+	X5c []string `json:"x5c"`
+
+	// key is the public key, set by parse when the JWKS is fetched.
+	key crypto.PublicKey
 }
 
-func (j *jwksKey) nAsBigInt() (*big.Int, error) {
-	data, err := base64.RawURLEncoding.DecodeString(j.N)
+// parse validates the key and sets its public key. Keys that are not
+// signing keys, have an unsupported type or curve, no algorithm (unless
+// inferAlg is set) or one that does not fit the key, or a certificate
+// chain that does not verify are rejected.
+func (j *jwksKey) parse(roots *x509.CertPool, inferAlg bool) error {
+	if j.Kid == "" {
+		return errors.New("missing kid")
+	}
+
+	if j.Alg == "" && !inferAlg {
+		return errors.New("missing alg")
+	}
+
+	if j.Use != "" && j.Use != "sig" {
+		return fmt.Errorf("not a signing key (use %q)", j.Use)
+	}
+
+	var err error
+
+	switch j.Kty {
+	case "RSA":
+		j.key, err = j.rsaPublicKey()
+	case "EC":
+		j.key, err = j.ecdsaPublicKey()
+	case "OKP":
+		j.key, err = j.ed25519PublicKey()
+	default:
+		return fmt.Errorf("unsupported key type %q", j.Kty)
+	}
+
 	if err != nil {
-		return nil, fmt.Errorf("%w", err)
+		return err
 	}
 
-	n := big.NewInt(0)
-	n.SetBytes(data)
+	if j.Alg != "" && !j.supports(j.Alg) {
+		return fmt.Errorf("algorithm %s does not fit %s key", j.Alg, j.Kty)
+	}
 
-	return n, nil
+	return j.verifyCertificateChain(roots)
+}
+
+// supports reports whether tokens signed with alg can be verified with
+// the key: alg must be the algorithm declared for the key, or, for keys
+// accepted without one (see WithInferredKeyAlgorithms), fit the key.
+func (j *jwksKey) supports(alg string) bool {
+	if j.Alg != "" && alg != j.Alg {
+		return false
+	}
+
+	return slices.Contains(keyAlgorithms(j.key), alg)
 }
 
-func (j *jwksKey) publicKey() (*rsa.PublicKey, error) {
-	var public rsa.PublicKey
+// keyAlgorithms returns the signing algorithms that can be used with a
+// public key.
+func keyAlgorithms(key crypto.PublicKey) []string {
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return []string{"RS256", "RS384", "RS512"}
+	case *ecdsa.PublicKey:
+		switch k.Curve {
+		case elliptic.P256():
+			return []string{"ES256"}
+		case elliptic.P384():
+			return []string{"ES384"}
+		}
+	case ed25519.PublicKey:
+		return []string{"EdDSA"}
+	}
+
+	return nil
+}
 
+// minRSAKeyBits is the smallest RSA modulus accepted in a JWKS.
+const minRSAKeyBits = 2048
+
+func (j *jwksKey) rsaPublicKey() (*rsa.PublicKey, error) {
 	n, err := j.nAsBigInt()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid n: %w", err)
+	}
+
+	if n.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA key is %d bits, at least %d required", n.BitLen(), minRSAKeyBits)
 	}
 
 	e, err := j.eAsInt()
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("invalid e: %w", err)
+	}
+
+	if e < 3 || e%2 == 0 {
+		return nil, fmt.Errorf("invalid RSA exponent %d", e)
+	}
+
+	return &rsa.PublicKey{N: n, E: e}, nil
+}
+
+func (j *jwksKey) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+
+	switch j.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", j.Crv)
+	}
+
+	size := (curve.Params().BitSize + 7) / 8
+
+	x, err := decodeCoordinate(j.X, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
+
+	y, err := decodeCoordinate(j.Y, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid y: %w", err)
+	}
+
+	public := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+
+	// ECDH validates that the point is on the curve.
+	if _, err := public.ECDH(); err != nil {
+		return nil, fmt.Errorf("invalid %s point: %w", j.Crv, err)
+	}
+
+	return public, nil
+}
+
+// decodeCoordinate decodes a base64url curve coordinate of the given
+// size in bytes.
+func decodeCoordinate(value string, size int) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if len(data) != size {
+		return nil, fmt.Errorf("expected %d bytes, got %d", size, len(data))
+	}
+
+	return new(big.Int).SetBytes(data), nil
+}
+
+func (j *jwksKey) ed25519PublicKey() (ed25519.PublicKey, error) {
+	if j.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported curve %q", j.Crv)
 	}
 
-	public.E = e
-	public.N = n
+	data, err := base64.RawURLEncoding.DecodeString(j.X)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x: %w", err)
+	}
 
-	return &public, nil
+	if len(data) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid x: expected %d bytes, got %d", ed25519.PublicKeySize, len(data))
+	}
+
+	return ed25519.PublicKey(data), nil
+}
+
+// verifyCertificateChain checks that the first x5c certificate holds
+// the key, and, if roots are given, that the chain verifies against
+// them. Keys without a chain are rejected when roots are given.
+func (j *jwksKey) verifyCertificateChain(roots *x509.CertPool) error {
+	if len(j.X5c) == 0 {
+		if roots != nil {
+			return errors.New("missing x5c certificate chain")
+		}
+
+		return nil
+	}
+
+	certs := make([]*x509.Certificate, 0, len(j.X5c))
+
+	for i, encoded := range j.X5c {
+		// x5c uses standard base64, not base64url.
+		der, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("invalid x5c certificate %d: %w", i, err)
+		}
+
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return fmt.Errorf("invalid x5c certificate %d: %w", i, err)
+		}
+
+		certs = append(certs, cert)
+	}
+
+	leaf, ok := certs[0].PublicKey.(interface{ Equal(x crypto.PublicKey) bool })
+	if !ok || !leaf.Equal(j.key) {
+		return errors.New("x5c certificate does not match the key")
+	}
+
+	if roots == nil {
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return fmt.Errorf("x5c certificate chain: %w", err)
+	}
+
+	return nil
+}
+
+func (j *jwksKey) nAsBigInt() (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(j.N)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	n := big.NewInt(0)
+	n.SetBytes(data)
+
+	return n, nil
 }
 
 func (j *jwksKey) eAsInt() (int, error) {
@@ -292,6 +608,10 @@ func (j *jwksKey) eAsInt() (int, error) {
 		return -1, fmt.Errorf("%w", err)
 	}
 
+	if len(data) > 8 {
+		return -1, errors.New("exponent value too large")
+	}
+
 	var ebytes []byte
 	// ensure we have padding if needed
 	if len(data) < 8 {
@@ -326,4 +646,39 @@ type jwksResponse struct {
 	Keys         []jwksKey                  `json:"keys"`
 	KeysMetadata map[string]jwksKeyMetadata `json:"keysMeta"`
 	MaxTokenTTL  int                        `json:"maxTokenTTL"`
+
+	// rejected describes the keys dropped by parseKeys.
+	rejected []string
+}
+
+// parseKeys validates the keys, dropping the ones that cannot be used
+// so that malformed keys are rejected once, when the JWKS is fetched,
+// rather than on every request. A JWKS may publish encryption keys or
+// key types we do not support next to its signing keys.
+func (r *jwksResponse) parseKeys(roots *x509.CertPool, inferAlg bool) {
+	keys := r.Keys[:0]
+
+	for _, key := range r.Keys {
+		if err := key.parse(roots, inferAlg); err != nil {
+			r.rejected = append(r.rejected, fmt.Sprintf("key %q: %v", key.Kid, err))
+
+			continue
+		}
+
+		keys = append(keys, key)
+	}
+
+	r.Keys = keys
+}
+
+// usable returns an error unless the JWKS has at least one usable key.
+func (r *jwksResponse) usable() error {
+	switch {
+	case len(r.Keys) > 0:
+		return nil
+	case len(r.rejected) > 0:
+		return fmt.Errorf("jwks contains no usable keys: %s", strings.Join(r.rejected, "; "))
+	default:
+		return errors.New("jwks contains no keys")
+	}
 }
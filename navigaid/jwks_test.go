@@ -0,0 +1,430 @@
+package navigaid_test
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// jwk returns the JWK of a public key.
+func jwk(t *testing.T, kid string, key crypto.PublicKey) map[string]any {
+	t.Helper()
+
+	b64 := base64.RawURLEncoding.EncodeToString
+
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		return map[string]any{
+			"kty": "RSA", "use": "sig", "alg": "RS256", "kid": kid,
+			"n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PublicKey:
+		return map[string]any{
+			"kty": "EC", "use": "sig", "alg": "ES256", "crv": "P-256", "kid": kid,
+			"x": b64(k.X.FillBytes(make([]byte, 32))), "y": b64(k.Y.FillBytes(make([]byte, 32))),
+		}
+	case ed25519.PublicKey:
+		return map[string]any{"kty": "OKP", "alg": "EdDSA", "crv": "Ed25519", "kid": kid, "x": b64(k)}
+	}
+
+	t.Fatalf("unsupported key %T", key)
+
+	return nil
+}
+
+// jwksServer serves a JWKS with the given keys.
+func jwksServer(t *testing.T, keys ...map[string]any) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// signToken returns an access token signed with key.
+func signToken(t *testing.T, method jwt.SigningMethod, kid string, key crypto.PrivateKey) string {
+	t.Helper()
+
+	claims := navigaid.Claims{Org: "acme", TokenType: navigaid.TokenTypeAccessToken}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+
+	return signed
+}
+
+func TestJWKSValidateToken(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := jwksServer(t,
+		jwk(t, "rsa", &rsaKey.PublicKey),
+		jwk(t, "ec", &ecKey.PublicKey),
+		jwk(t, "ed", edPublic),
+	)
+
+	jwks := navigaid.NewJWKS(server.URL)
+
+	valid := map[string]string{
+		"RS256": signToken(t, jwt.SigningMethodRS256, "rsa", rsaKey),
+		"ES256": signToken(t, jwt.SigningMethodES256, "ec", ecKey),
+		"EdDSA": signToken(t, jwt.SigningMethodEdDSA, "ed", edKey),
+	}
+
+	for alg, token := range valid {
+		claims, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", alg, err)
+
+			continue
+		}
+
+		if claims.Org != "acme" {
+			t.Errorf("%s: unexpected claims %+v", alg, claims)
+		}
+	}
+
+	invalid := map[string]string{
+		// The JWK declares RS256.
+		"alg mismatch": signToken(t, jwt.SigningMethodRS384, "rsa", rsaKey),
+		// ES384 does not fit a P-256 key.
+		"curve mismatch": signToken(t, jwt.SigningMethodES384, "ec", p384Key),
+		"wrong key type": signToken(t, jwt.SigningMethodEdDSA, "ec", edKey),
+	}
+
+	for name, token := range invalid {
+		if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestJWKSRejectsMalformedKeys(t *testing.T) {
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offCurve := jwk(t, "off-curve", &ecKey.PublicKey)
+	offCurve["y"] = offCurve["x"]
+
+	encryption := jwk(t, "enc", edPublic)
+	encryption["use"] = "enc"
+
+	noAlg := jwk(t, "no-alg", edPublic)
+	delete(noAlg, "alg")
+
+	malformed := []map[string]any{
+		offCurve,
+		encryption,
+		noAlg,
+		{"kty": "RSA", "kid": "small", "alg": "RS256", "n": "AQAB", "e": "AQAB"},
+		{"kty": "EC", "kid": "curve", "alg": "ES512", "crv": "P-521", "x": "AA", "y": "AA"},
+		{"kty": "OKP", "kid": "short", "alg": "EdDSA", "crv": "Ed25519", "x": "AAAA"},
+		{"kty": "oct", "kid": "secret", "alg": "HS256", "k": "c2VjcmV0"},
+		{"kty": "OKP", "kid": "alg", "crv": "Ed25519", "alg": "RS256", "x": encryption["x"]},
+	}
+
+	t.Run("no usable keys", func(t *testing.T) {
+		err := navigaid.NewJWKS(jwksServer(t, malformed...).URL).Ping(context.Background())
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+
+		for _, kid := range []string{"off-curve", "enc", "no-alg", "small", "curve", "short", "secret", "alg"} {
+			if !strings.Contains(err.Error(), `"`+kid+`"`) {
+				t.Errorf("Expected key %q to be reported in %v", kid, err)
+			}
+		}
+	})
+
+	t.Run("malformed keys are skipped", func(t *testing.T) {
+		jwks := navigaid.NewJWKS(jwksServer(t, append(malformed, jwk(t, "ed", edPublic))...).URL)
+
+		if err := jwks.Ping(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		token := signToken(t, jwt.SigningMethodEdDSA, "ed", edKey)
+		if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err != nil {
+			t.Errorf("Unexpected error: %v", err)
+		}
+
+		token = signToken(t, jwt.SigningMethodEdDSA, "enc", edKey)
+		if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err == nil {
+			t.Error("Expected the encryption key to be rejected")
+		}
+	})
+}
+
+func TestWithInferredKeyAlgorithms(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := jwk(t, "ec", &ecKey.PublicKey)
+	delete(key, "alg")
+
+	server := jwksServer(t, key)
+	token := signToken(t, jwt.SigningMethodES256, "ec", ecKey)
+
+	if _, err := navigaid.NewJWKS(server.URL).ValidateToken(token, navigaid.TokenTypeAccessToken); err == nil {
+		t.Error("Expected a key without alg to be rejected by default")
+	}
+
+	jwks := navigaid.NewJWKS(server.URL, navigaid.WithInferredKeyAlgorithms())
+
+	if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// ES384 does not fit a P-256 key.
+	p384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token = signToken(t, jwt.SigningMethodES384, "ec", p384Key)
+	if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err == nil {
+		t.Error("Expected an algorithm that does not fit the key to be rejected")
+	}
+}
+
+func TestJWKSRefreshWithoutUsableKeys(t *testing.T) {
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		fetches  atomic.Int32
+		rejected atomic.Bool
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+
+		key := jwk(t, "ed", edPublic)
+		if rejected.Load() {
+			key["use"] = "enc"
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{key}})
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+
+	jwks := navigaid.NewJWKS(server.URL,
+		navigaid.WithJwksTTL(time.Nanosecond),
+		navigaid.WithJwksLogger(slog.New(slog.NewTextHandler(&logs, nil))),
+	)
+
+	token := signToken(t, jwt.SigningMethodEdDSA, "ed", edKey)
+
+	if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// The endpoint now only publishes keys that are rejected.
+	rejected.Store(true)
+	time.Sleep(time.Millisecond)
+
+	for range 2 {
+		if _, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken); err != nil {
+			t.Fatalf("Expected the cached key to be kept, got %v", err)
+		}
+	}
+
+	if n := fetches.Load(); n != 2 {
+		t.Errorf("Expected the refresh to back off after the failed fetch, got %d fetches", n)
+	}
+
+	if !strings.Contains(logs.String(), "Rejected JWKS keys") || !strings.Contains(logs.String(), "not a signing key") {
+		t.Errorf("Expected the rejected keys to be logged, got %q", logs.String())
+	}
+}
+
+func TestJWKSReady(t *testing.T) {
+	edPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var fetches atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fetches.Add(1)
+
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []any{jwk(t, "ed", edPublic)}})
+	}))
+	defer server.Close()
+
+	jwks := navigaid.NewJWKS(server.URL)
+
+	for range 3 {
+		if err := jwks.Ready(context.Background()); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("Expected cached keys to be used, got %d fetches", n)
+	}
+
+	server.Close()
+
+	if err := navigaid.NewJWKS(server.URL).Ready(context.Background()); err == nil {
+		t.Error("Expected an error without cached keys")
+	}
+}
+
+func TestWithX5CRoots(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "signing key"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, ca, edPublic, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	chained := jwk(t, "chained", edPublic)
+	chained["x5c"] = []string{base64.StdEncoding.EncodeToString(leafDER)}
+
+	otherPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mismatched := jwk(t, "mismatched", otherPublic)
+	mismatched["x5c"] = chained["x5c"]
+
+	unchained := jwk(t, "unchained", edPublic)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	server := jwksServer(t, chained, mismatched, unchained)
+
+	tests := map[string]struct {
+		options []navigaid.JWKSOption
+		valid   map[string]bool
+	}{
+		"without roots": {
+			valid: map[string]bool{"chained": true, "mismatched": false, "unchained": true},
+		},
+		"with roots": {
+			options: []navigaid.JWKSOption{navigaid.WithX5CRoots(roots)},
+			valid:   map[string]bool{"chained": true, "mismatched": false, "unchained": false},
+		},
+		"with other roots": {
+			options: []navigaid.JWKSOption{navigaid.WithX5CRoots(x509.NewCertPool())},
+			valid:   map[string]bool{"chained": false, "mismatched": false, "unchained": false},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			jwks := navigaid.NewJWKS(server.URL, tc.options...)
+
+			for kid, valid := range tc.valid {
+				token := signToken(t, jwt.SigningMethodEdDSA, kid, edKey)
+
+				_, err := jwks.ValidateToken(token, navigaid.TokenTypeAccessToken)
+				if valid && err != nil {
+					t.Errorf("%s: unexpected error: %v", kid, err)
+				}
+
+				if !valid && err == nil {
+					t.Errorf("%s: expected an error", kid)
+				}
+			}
+		})
+	}
+}
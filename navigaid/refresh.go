@@ -22,8 +22,14 @@ type cachedToken struct {
 
 // NewTokenRefresher creates a new token refresher.
 func NewTokenRefresher(logger *slog.Logger, tokenEndpoint string) *TokenRefresher {
+	return NewServiceTokenRefresher(logger, New(tokenEndpoint))
+}
+
+// NewServiceTokenRefresher creates a new token refresher that gets its
+// access tokens from service.
+func NewServiceTokenRefresher(logger *slog.Logger, service *AccessTokenService) *TokenRefresher {
 	return &TokenRefresher{
-		service:    New(tokenEndpoint),
+		service:    service,
 		logger:     logger,
 		tokenCache: make(map[string]*cachedToken),
 	}
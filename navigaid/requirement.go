@@ -0,0 +1,524 @@
+package navigaid
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Caller is what a Requirement is evaluated against.
+type Caller struct {
+	Claims Claims
+	// Unit is the unit the request applies to, if known. It is used by
+	// UnitPermission.
+	Unit string
+}
+
+// Requirement is a boolean expression over the claims of a caller,
+// built with AllOf, AnyOf, Not, Permission, PermissionInUnit,
+// UnitPermission and Group, or parsed with ParseRequirement.
+type Requirement interface {
+	// Check reports whether the caller satisfies the requirement,
+	// and explains why or why not.
+	Check(caller Caller) (bool, string)
+	// String returns the requirement in the syntax of
+	// ParseRequirement.
+	String() string
+}
+
+// RequirementError is returned by CheckRequirement when a caller does
+// not satisfy a requirement.
+type RequirementError struct {
+	Requirement Requirement
+	// Reason explains why the requirement is not met, e.g.
+	// "lacks permission articles:write".
+	Reason string
+}
+
+func (e *RequirementError) Error() string {
+	return "requirement " + e.Requirement.String() + " not met: " + e.Reason
+}
+
+// CheckRequirement returns a *RequirementError if the caller does not
+// satisfy the requirement. A nil requirement is always satisfied.
+func CheckRequirement(requirement Requirement, caller Caller) error {
+	if requirement == nil {
+		return nil
+	}
+
+	if ok, reason := requirement.Check(caller); !ok {
+		return &RequirementError{Requirement: requirement, Reason: reason}
+	}
+
+	return nil
+}
+
+// Permission requires an organisation-level permission.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func Permission(name string) Requirement {
+	return permission{name: name}
+}
+
+// Permissions requires all of the given organisation-level
+// permissions.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func Permissions(names ...string) Requirement {
+	requirements := make([]Requirement, len(names))
+	for i, name := range names {
+		requirements[i] = Permission(name)
+	}
+
+	return AllOf(requirements...)
+}
+
+// PermissionInUnit requires a permission in the given unit, either
+// granted in the unit or inherited from the organisation.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func PermissionInUnit(unit, name string) Requirement {
+	return permission{name: name, unit: unit, inUnit: true}
+}
+
+// UnitPermission requires a permission in the unit of the request,
+// Caller.Unit. It is not satisfied if the unit is unknown.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func UnitPermission(name string) Requirement {
+	return permission{name: name, inUnit: true}
+}
+
+type permission struct {
+	name   string
+	unit   string
+	inUnit bool
+}
+
+func (p permission) Check(caller Caller) (bool, string) {
+	if !p.inUnit {
+		if caller.Claims.HasPermissionsInOrganisation(p.name) {
+			return true, "has permission " + p.name
+		}
+
+		return false, "lacks permission " + p.name
+	}
+
+	unit := p.unit
+	if unit == "" {
+		unit = caller.Unit
+	}
+
+	if unit == "" {
+		return false, "no unit to check permission " + p.name + " in"
+	}
+
+	if caller.Claims.HasPermissionsInUnit(unit, p.name) {
+		return true, "has permission " + p.name + " in unit " + unit
+	}
+
+	return false, "lacks permission " + p.name + " in unit " + unit
+}
+
+func (p permission) String() string {
+	switch {
+	case !p.inUnit:
+		return p.name
+	case p.unit == "":
+		return "unit(" + p.name + ")"
+	default:
+		return "unit(" + p.unit + ", " + p.name + ")"
+	}
+}
+
+// Group requires membership of a group, listed in Claims.Groups.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func Group(name string) Requirement {
+	return group(name)
+}
+
+type group string
+
+func (g group) Check(caller Caller) (bool, string) {
+	if slices.Contains(caller.Claims.Groups, string(g)) {
+		return true, "member of group " + string(g)
+	}
+
+	return false, "not a member of group " + string(g)
+}
+
+func (g group) String() string {
+	return "group(" + string(g) + ")"
+}
+
+// AllOf requires all of the given requirements. An empty AllOf is
+// always satisfied.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func AllOf(requirements ...Requirement) Requirement {
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+
+	return allOf(requirements)
+}
+
+type allOf []Requirement
+
+func (a allOf) Check(caller Caller) (bool, string) {
+	var met, unmet []string
+
+	for _, requirement := range a {
+		if ok, reason := requirement.Check(caller); ok {
+			met = append(met, reason)
+		} else {
+			unmet = append(unmet, reason)
+		}
+	}
+
+	if len(unmet) > 0 {
+		return false, strings.Join(unmet, " and ")
+	}
+
+	if len(met) == 0 {
+		return true, "nothing required"
+	}
+
+	return true, strings.Join(met, " and ")
+}
+
+func (a allOf) String() string {
+	return join(a, " and ", "all()")
+}
+
+// AnyOf requires at least one of the given requirements. An empty
+// AnyOf is never satisfied.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func AnyOf(requirements ...Requirement) Requirement {
+	if len(requirements) == 1 {
+		return requirements[0]
+	}
+
+	return anyOf(requirements)
+}
+
+type anyOf []Requirement
+
+func (a anyOf) Check(caller Caller) (bool, string) {
+	unmet := make([]string, 0, len(a))
+
+	for _, requirement := range a {
+		ok, reason := requirement.Check(caller)
+		if ok {
+			return true, reason
+		}
+
+		unmet = append(unmet, reason)
+	}
+
+	if len(unmet) == 0 {
+		return false, "no alternatives allowed"
+	}
+
+	return false, strings.Join(unmet, " and ")
+}
+
+func (a anyOf) String() string {
+	return join(a, " or ", "any()")
+}
+
+// Not requires that the given requirement is not satisfied.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func Not(requirement Requirement) Requirement {
+	return not{requirement}
+}
+
+type not struct {
+	requirement Requirement
+}
+
+func (n not) Check(caller Caller) (bool, string) {
+	ok, reason := n.requirement.Check(caller)
+
+	return !ok, reason
+}
+
+func (n not) String() string {
+	return "not " + operand(n.requirement)
+}
+
+// join formats the operands of AllOf and AnyOf.
+func join(requirements []Requirement, separator, empty string) string {
+	if len(requirements) == 0 {
+		return empty
+	}
+
+	parts := make([]string, len(requirements))
+	for i, requirement := range requirements {
+		parts[i] = operand(requirement)
+	}
+
+	return strings.Join(parts, separator)
+}
+
+// operand formats a requirement used as an operand, in parentheses if
+// it is an AllOf or AnyOf.
+func operand(requirement Requirement) string {
+	switch r := requirement.(type) {
+	case allOf:
+		if len(r) > 0 {
+			return "(" + r.String() + ")"
+		}
+	case anyOf:
+		if len(r) > 0 {
+			return "(" + r.String() + ")"
+		}
+	}
+
+	return requirement.String()
+}
+
+// MustParseRequirement is ParseRequirement for requirements known to
+// be valid, such as literals. It panics on errors.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func MustParseRequirement(expression string) Requirement {
+	requirement, err := ParseRequirement(expression)
+	if err != nil {
+		panic(err)
+	}
+
+	return requirement
+}
+
+// ParseRequirement parses a requirement expression, as found in
+// configuration files:
+//
+//	articles:read                       organisation permission
+//	unit(articles:read)                 permission in the request's unit
+//	unit(unit-a, articles:read)         permission in unit-a
+//	group(editors)                      group membership
+//	a and b, a && b                     both
+//	a or b, a || b                      either
+//	not a, !a                           negation
+//	(a or b) and not group(suspended)   grouping
+//
+// "not" binds tighter than "and", which binds tighter than "or".
+// all() and any() are the empty AllOf and AnyOf.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func ParseRequirement(expression string) (Requirement, error) {
+	p := &requirementParser{tokens: tokenizeRequirement(expression)}
+
+	requirement, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("invalid requirement %q: %w", expression, err)
+	}
+
+	if tok := p.peek(); tok != "" {
+		return nil, fmt.Errorf("invalid requirement %q: unexpected %q", expression, tok)
+	}
+
+	return requirement, nil
+}
+
+// tokenizeRequirement splits an expression into words and the
+// punctuation tokens (, ), ",", !, && and ||.
+func tokenizeRequirement(expression string) []string {
+	var (
+		tokens []string
+		word   strings.Builder
+	)
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(expression); i++ {
+		c := expression[i]
+
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			flush()
+		case c == '(' || c == ')' || c == ',' || c == '!':
+			flush()
+			tokens = append(tokens, string(c))
+		case (c == '&' || c == '|') && i+1 < len(expression) && expression[i+1] == c:
+			flush()
+			tokens = append(tokens, expression[i:i+2])
+			i++
+		default:
+			word.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return tokens
+}
+
+type requirementParser struct {
+	tokens []string
+}
+
+func (p *requirementParser) peek() string {
+	if len(p.tokens) == 0 {
+		return ""
+	}
+
+	return p.tokens[0]
+}
+
+func (p *requirementParser) next() string {
+	tok := p.peek()
+	if tok != "" {
+		p.tokens = p.tokens[1:]
+	}
+
+	return tok
+}
+
+func (p *requirementParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		if got == "" {
+			return fmt.Errorf("expected %q at end of expression", tok)
+		}
+
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+
+	return nil
+}
+
+//nolint:ireturn // Requirements are combined through the interface
+func (p *requirementParser) parseOr() (Requirement, error) {
+	return p.parseList(func(tok string) bool { return tok == "or" || tok == "||" }, p.parseAnd, AnyOf)
+}
+
+//nolint:ireturn // Requirements are combined through the interface
+func (p *requirementParser) parseAnd() (Requirement, error) {
+	return p.parseList(func(tok string) bool { return tok == "and" || tok == "&&" }, p.parseUnary, AllOf)
+}
+
+// parseList parses operands separated by an operator.
+//
+//nolint:ireturn // Requirements are combined through the interface
+func (p *requirementParser) parseList(
+	isOperator func(string) bool,
+	parseOperand func() (Requirement, error),
+	combine func(...Requirement) Requirement,
+) (Requirement, error) {
+	first, err := parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	operands := []Requirement{first}
+
+	for isOperator(p.peek()) {
+		p.next()
+
+		operand, err := parseOperand()
+		if err != nil {
+			return nil, err
+		}
+
+		operands = append(operands, operand)
+	}
+
+	return combine(operands...), nil
+}
+
+//nolint:ireturn // Requirements are combined through the interface
+func (p *requirementParser) parseUnary() (Requirement, error) {
+	tok := p.next()
+
+	switch tok {
+	case "":
+		return nil, errors.New("unexpected end of expression")
+	case "not", "!":
+		requirement, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return Not(requirement), nil
+	case "(":
+		requirement, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		return requirement, p.expect(")")
+	case ")", ",", "and", "&&", "or", "||":
+		return nil, fmt.Errorf("unexpected %q", tok)
+	}
+
+	if p.peek() != "(" {
+		return Permission(tok), nil
+	}
+
+	p.next()
+
+	args, err := p.parseArguments()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case tok == "all" && len(args) == 0:
+		return allOf{}, nil
+	case tok == "any" && len(args) == 0:
+		return anyOf{}, nil
+	case tok == "group" && len(args) == 1:
+		return Group(args[0]), nil
+	case tok == "unit" && len(args) == 1:
+		return UnitPermission(args[0]), nil
+	case tok == "unit" && len(args) == 2:
+		return PermissionInUnit(args[0], args[1]), nil
+	default:
+		return nil, fmt.Errorf("unknown function %s with %d arguments", tok, len(args))
+	}
+}
+
+// parseArguments parses the comma separated words of a function call,
+// after the opening parenthesis.
+func (p *requirementParser) parseArguments() ([]string, error) {
+	var args []string
+
+	if p.peek() == ")" {
+		p.next()
+
+		return args, nil
+	}
+
+	for {
+		arg := p.next()
+
+		switch arg {
+		case "":
+			return nil, errors.New("expected \")\" at end of expression")
+		case "(", ")", ",", "!", "&&", "||":
+			return nil, fmt.Errorf("unexpected %q in arguments", arg)
+		}
+
+		args = append(args, arg)
+
+		switch tok := p.next(); tok {
+		case ")":
+			return args, nil
+		case ",":
+		case "":
+			return nil, errors.New("expected \")\" at end of expression")
+		default:
+			return nil, fmt.Errorf("expected \",\" or \")\", got %q", tok)
+		}
+	}
+}
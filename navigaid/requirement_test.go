@@ -0,0 +1,158 @@
+package navigaid_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+func TestParseRequirement(t *testing.T) {
+	caller := navigaid.Caller{
+		Claims: navigaid.Claims{
+			Groups: []string{"editors"},
+			Permissions: navigaid.PermissionsClaim{
+				Org:   []string{"articles:read"},
+				Units: map[string][]string{"unit-a": {"articles:write"}},
+			},
+		},
+		Unit: "unit-a",
+	}
+
+	tests := []struct {
+		expression string
+		canonical  string
+		allowed    bool
+		reason     string
+	}{
+		{
+			expression: "articles:read",
+			canonical:  "articles:read",
+			allowed:    true,
+			reason:     "has permission articles:read",
+		},
+		{
+			expression: "articles:read and articles:delete",
+			canonical:  "articles:read and articles:delete",
+			reason:     "lacks permission articles:delete",
+		},
+		{
+			expression: "articles:delete || group(editors)",
+			canonical:  "articles:delete or group(editors)",
+			allowed:    true,
+			reason:     "member of group editors",
+		},
+		{
+			expression: "articles:delete or group(admins)",
+			canonical:  "articles:delete or group(admins)",
+			reason:     "lacks permission articles:delete and not a member of group admins",
+		},
+		{
+			expression: "articles:read && !group(editors)",
+			canonical:  "articles:read and not group(editors)",
+			reason:     "member of group editors",
+		},
+		{
+			expression: "(articles:delete or group(editors)) and not group(suspended)",
+			canonical:  "(articles:delete or group(editors)) and not group(suspended)",
+			allowed:    true,
+			reason:     "member of group editors and not a member of group suspended",
+		},
+		{
+			expression: "articles:delete or articles:read and group(admins)",
+			canonical:  "articles:delete or (articles:read and group(admins))",
+			reason:     "lacks permission articles:delete and not a member of group admins",
+		},
+		{
+			expression: "unit(articles:write)",
+			canonical:  "unit(articles:write)",
+			allowed:    true,
+			reason:     "has permission articles:write in unit unit-a",
+		},
+		{
+			expression: "unit(unit-b, articles:write)",
+			canonical:  "unit(unit-b, articles:write)",
+			reason:     "lacks permission articles:write in unit unit-b",
+		},
+		{
+			expression: "all()",
+			canonical:  "all()",
+			allowed:    true,
+			reason:     "nothing required",
+		},
+		{
+			expression: "any()",
+			canonical:  "any()",
+			reason:     "no alternatives allowed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			requirement, err := navigaid.ParseRequirement(tt.expression)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+
+			if requirement.String() != tt.canonical {
+				t.Errorf("Expected %q, got %q", tt.canonical, requirement.String())
+			}
+
+			reparsed, err := navigaid.ParseRequirement(requirement.String())
+			if err != nil || reparsed.String() != tt.canonical {
+				t.Errorf("String() does not round-trip: %q, %v", reparsed, err)
+			}
+
+			allowed, reason := requirement.Check(caller)
+			if allowed != tt.allowed || reason != tt.reason {
+				t.Errorf("Expected (%v, %q), got (%v, %q)", tt.allowed, tt.reason, allowed, reason)
+			}
+		})
+	}
+}
+
+func TestParseRequirementErrors(t *testing.T) {
+	for _, expression := range []string{
+		"",
+		"articles:read and",
+		"(articles:read",
+		"articles:read)",
+		"group()",
+		"group(a, b)",
+		"unit(a, b, c)",
+		"role(admin)",
+		"group(editors",
+		"not",
+		"a or or b",
+	} {
+		if _, err := navigaid.ParseRequirement(expression); err == nil {
+			t.Errorf("Expected %q to be rejected", expression)
+		}
+	}
+}
+
+func TestCheckRequirement(t *testing.T) {
+	caller := navigaid.Caller{Claims: navigaid.Claims{Groups: []string{"suspended"}}}
+
+	if err := navigaid.CheckRequirement(nil, caller); err != nil {
+		t.Errorf("Expected nil requirement to be satisfied, got %v", err)
+	}
+
+	requirement := navigaid.AllOf(navigaid.Permissions(), navigaid.Not(navigaid.Group("suspended")))
+
+	err := navigaid.CheckRequirement(requirement, caller)
+
+	var requirementErr *navigaid.RequirementError
+	if !errors.As(err, &requirementErr) {
+		t.Fatalf("Expected a RequirementError, got %v", err)
+	}
+
+	expected := "requirement all() and not group(suspended) not met: member of group suspended"
+	if err.Error() != expected {
+		t.Errorf("Expected %q, got %q", expected, err.Error())
+	}
+
+	if err := navigaid.CheckRequirement(navigaid.UnitPermission("articles:read"), caller); err == nil {
+		t.Error("Expected unit permission without a unit to be denied")
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"strings"
+	"sync"
 	"time"
 
 	"connectrpc.com/connect"
@@ -19,11 +21,13 @@ import (
 	"go.opentelemetry.io/contrib/instrumentation/github.com/aws/aws-lambda-go/otellambda"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/metric"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Constants for telemetry.
@@ -92,7 +96,10 @@ func (p *Provider) Initialize(ctx context.Context, serviceName string, opts dind
 	return shutdown, nil
 }
 
-// Interceptor implements dindenault.TelemetryProvider.
+// Interceptor implements dindenault.TelemetryProvider. It records
+// metrics for unary and streaming calls.
+//
+//nolint:ireturn // Returning interface as intended by TelemetryProvider design
 func (p *Provider) Interceptor(logger *slog.Logger, opts dindenault.TelemetryOptions) connect.Interceptor {
 	// We use the logger for debugging in case of initialization errors
 	logger.Debug("Creating OpenTelemetry interceptor")
@@ -113,72 +120,158 @@ func (p *Provider) Interceptor(logger *slog.Logger, opts dindenault.TelemetryOpt
 		metric.WithUnit("ms"),
 	)
 
-	// Context key for start time
-	type startTimeKey struct{}
+	return &interceptor{
+		opts:              opts,
+		requestCounter:    requestCounter,
+		responseCounter:   responseCounter,
+		durationHistogram: durationHistogram,
+	}
+}
 
-	var startTimeContextKey = startTimeKey{}
+// interceptor records metrics for unary calls and for streams, both in
+// clients and in handlers. A stream is measured from its start until
+// the handler returns or the client closes the response.
+type interceptor struct {
+	opts              dindenault.TelemetryOptions
+	requestCounter    metric.Int64Counter
+	responseCounter   metric.Int64Counter
+	durationHistogram metric.Float64Histogram
+}
 
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Extract service and method information
-			procedure := req.Spec().Procedure
-			service, method := ExtractServiceAndMethod(procedure)
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		finish := i.start(ctx, req.Spec().Procedure)
 
-			// Get organization from context
-			organization := UnknownValue
-			if opts.OrganizationFn != nil {
-				organization = opts.OrganizationFn(ctx)
-			}
+		// Call the next handler
+		resp, err := next(ctx, req)
 
-			// Common attributes for all metrics
-			commonAttrs := []attribute.KeyValue{
-				attribute.String("service", service),
-				attribute.String("method", method),
-				attribute.String("organization", organization),
-			}
+		finish(err)
 
-			// Record start time
-			startTime := time.Now()
-			ctx = context.WithValue(ctx, startTimeContextKey, startTime)
+		return resp, err
+	}
+}
 
-			// Record request metric
-			requestCounter.Add(ctx, 1, metric.WithAttributes(commonAttrs...))
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &streamingClientConn{
+			StreamingClientConn: next(ctx, spec),
+			finish:              i.start(ctx, spec.Procedure),
+		}
+	}
+}
 
-			// Call the next handler
-			resp, err := next(ctx, req)
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		finish := i.start(ctx, conn.Spec().Procedure)
 
-			// Determine status code
-			status := "success"
+		err := next(ctx, conn)
 
-			if err != nil {
-				var connectErr *connect.Error
-				if errors.As(err, &connectErr) {
-					status = connectErr.Code().String()
-				} else {
-					status = "error"
-				}
-			}
+		finish(err)
 
-			// Response attributes include status
-			// Copy commonAttrs and add status
-			responseAttrs := make([]attribute.KeyValue, len(commonAttrs)+1)
-			copy(responseAttrs, commonAttrs)
-			responseAttrs[len(commonAttrs)] = attribute.String("status", status)
-
-			// Record response metric
-			responseCounter.Add(ctx, 1, metric.WithAttributes(responseAttrs...))
-
-			// Calculate and record duration
-			if startTimeVal := ctx.Value(startTimeContextKey); startTimeVal != nil {
-				if startTime, ok := startTimeVal.(time.Time); ok {
-					duration := time.Since(startTime)
-					durationHistogram.Record(ctx, float64(duration.Milliseconds()), metric.WithAttributes(commonAttrs...))
-				}
-			}
+		return err
+	}
+}
+
+// start records a request and returns the function that records its
+// response and duration.
+func (i *interceptor) start(ctx context.Context, procedure string) func(error) {
+	// Extract service and method information
+	service, method := ExtractServiceAndMethod(procedure)
+
+	// Get organization from context
+	organization := UnknownValue
+	if i.opts.OrganizationFn != nil {
+		organization = i.opts.OrganizationFn(ctx)
+	}
+
+	// Common attributes for all metrics
+	commonAttrs := []attribute.KeyValue{
+		attribute.String("service", service),
+		attribute.String("method", method),
+		attribute.String("organization", organization),
+	}
 
-			return resp, err
+	// Record start time
+	startTime := time.Now()
+
+	// Record request metric
+	i.requestCounter.Add(ctx, 1, metric.WithAttributes(commonAttrs...))
+
+	return func(err error) {
+		// Determine status code
+		status := "success"
+
+		if err != nil {
+			var connectErr *connect.Error
+			if errors.As(err, &connectErr) {
+				status = connectErr.Code().String()
+			} else {
+				status = "error"
+			}
 		}
+
+		// Response attributes include status
+		// Copy commonAttrs and add status
+		responseAttrs := make([]attribute.KeyValue, len(commonAttrs)+1)
+		copy(responseAttrs, commonAttrs)
+		responseAttrs[len(commonAttrs)] = attribute.String("status", status)
+
+		// Record response metric
+		i.responseCounter.Add(ctx, 1, metric.WithAttributes(responseAttrs...))
+
+		// Calculate and record duration
+		duration := time.Since(startTime)
+		i.durationHistogram.Record(ctx, float64(duration.Milliseconds()), metric.WithAttributes(commonAttrs...))
+	}
+}
+
+// streamingClientConn calls finish once with the outcome of a client
+// stream when its response is closed: the first error other than
+// io.EOF returned by Send, Receive or CloseResponse.
+type streamingClientConn struct {
+	connect.StreamingClientConn
+
+	finish func(error)
+	once   sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *streamingClientConn) Send(msg any) error {
+	return c.record(c.StreamingClientConn.Send(msg))
+}
+
+func (c *streamingClientConn) Receive(msg any) error {
+	return c.record(c.StreamingClientConn.Receive(msg))
+}
+
+func (c *streamingClientConn) CloseResponse() error {
+	err := c.record(c.StreamingClientConn.CloseResponse())
+
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.finish(c.err)
 	})
+
+	return err
+}
+
+func (c *streamingClientConn) record(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+
+	return err
 }
 
 // InstrumentHandler implements dindenault.TelemetryProvider.
@@ -187,6 +280,16 @@ func (p *Provider) InstrumentHandler(handler interface{}) interface{} {
 	return otellambda.InstrumentHandler(handler)
 }
 
+// ReportPanic implements dindenault.PanicReporter by recording the
+// panic as an exception on the active span and marking it as failed.
+func (p *Provider) ReportPanic(ctx context.Context, recovered any, stack []byte) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(fmt.Errorf("panic: %v", recovered), trace.WithAttributes(
+		semconv.ExceptionStacktraceKey.String(string(stack)),
+	))
+	span.SetStatus(codes.Error, "panic")
+}
+
 // Utility functions for telemetry
 
 // PutCloudWatchMetric sends a custom metric to CloudWatch.
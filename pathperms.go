@@ -5,9 +5,11 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 
 	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
 
 	"github.com/navigacontentlab/dindenault/navigaid"
 )
@@ -16,28 +18,129 @@ import (
 //
 // When several configurations match a request path, the longest
 // (most specific) PathPrefix wins. Paths that match no configuration
-// pass through without additional permission checks — define a
-// catch-all config (e.g. PathPrefix "/") if you want every path to
-// require permissions.
+// pass through without additional permission checks, unless the
+// PathPermissionPolicy denies them by default.
 type PathPermissionConfig struct {
 	// PathPrefix is the prefix of the request path
 	PathPrefix string
 	// Permissions are the organization-level permissions required
 	Permissions []string
+	// Public marks paths that need no permissions. In
+	// WithPathPermissionPolicyService they need no authentication
+	// either; PathPolicyInterceptors leaves authentication to the
+	// authentication interceptor.
+	//
+	// A public PathPrefix only matches on a segment boundary: the path
+	// itself and the paths below it, so "/api/status" matches
+	// "/api/status" and "/api/status/db" but not "/api/status-admin".
+	Public bool
+	// Unit, if set, returns the unit that a request applies to, and
+	// the Permissions are required in that unit instead of in the
+	// organisation. Connect requests pass the request message (see
+	// UnitField); plain HTTP services pass the *http.Request.
+	Unit UnitExtractor
+	// Require, if set, must also be satisfied, for requirements that
+	// are not a plain list of permissions:
+	//
+	//	Require: navigaid.MustParseRequirement("(articles:edit or group(admins)) and not group(suspended)")
+	//
+	// With Unit, UnitPermission requirements apply to the request's
+	// unit.
+	Require navigaid.Requirement
+}
+
+// requiredPermissions returns the Permissions of the configuration and
+// its Require expression, for audit events.
+func (c *PathPermissionConfig) requiredPermissions() []string {
+	if c.Require == nil {
+		return c.Permissions
+	}
+
+	return append(slices.Clip(c.Permissions), c.Require.String())
+}
+
+// PathPermissionPolicy is a set of path permission configurations and
+// what to do with paths that none of them match.
+type PathPermissionPolicy struct {
+	// Configs are the path permission configurations.
+	Configs []PathPermissionConfig
+
+	// DefaultDeny rejects requests whose path matches no configuration
+	// with permission denied, so that a new method is not reachable
+	// until it has been configured. Use Public configurations for
+	// paths that need no permissions.
+	DefaultDeny bool
+
+	// Services, if set, are compared with Configs when the interceptor
+	// or handler is created, and the problems found by Check are
+	// logged as warnings.
+	Services []protoreflect.ServiceDescriptor
+}
+
+// Check compares the policy with the methods of services. It returns a
+// description of every procedure that no configuration matches, every
+// configuration whose PathPrefix matches no procedure (a stale or
+// misspelled prefix), and every public configuration that also lists
+// permissions, a unit or a requirement.
+func (p PathPermissionPolicy) Check(services ...protoreflect.ServiceDescriptor) []string {
+	var (
+		problems   []string
+		procedures []string
+	)
+
+	for _, service := range services {
+		methods := service.Methods()
+
+		for i := range methods.Len() {
+			procedure := "/" + string(service.FullName()) + "/" + string(methods.Get(i).Name())
+			procedures = append(procedures, procedure)
+
+			if matchPathConfig(p.Configs, procedure) != nil {
+				continue
+			}
+
+			if p.DefaultDeny {
+				problems = append(problems, procedure+" matches no configuration and is denied")
+			} else {
+				problems = append(problems, procedure+" matches no configuration and is not protected")
+			}
+		}
+	}
+
+	for _, config := range p.Configs {
+		if config.Public && (len(config.Permissions) > 0 || config.Unit != nil || config.Require != nil) {
+			problems = append(problems, "prefix "+config.PathPrefix+" is public but lists permissions")
+		}
+
+		if len(services) > 0 && !slices.ContainsFunc(procedures, config.matches) {
+			problems = append(problems, "prefix "+config.PathPrefix+" matches no procedure")
+		}
+	}
+
+	return problems
+}
+
+// warnAboutProblems logs the problems Check finds with the policy's
+// services.
+func (p PathPermissionPolicy) warnAboutProblems(logger *slog.Logger) {
+	if len(p.Services) == 0 {
+		return
+	}
+
+	for _, problem := range p.Check(p.Services...) {
+		logger.Warn("Path permission policy problem", "problem", problem)
+	}
 }
 
 // matchPathConfig returns the configuration with the longest matching
-// PathPrefix, or nil if none matches. Matching is case-insensitive to
-// stay consistent with the App's request routing.
+// PathPrefix, or nil if none matches. Matching is case-insensitive even
+// though routing is not, so that a differently cased path can never
+// match a less restrictive configuration than intended.
 func matchPathConfig(configs []PathPermissionConfig, path string) *PathPermissionConfig {
-	path = strings.ToLower(path)
-
 	var matched *PathPermissionConfig
 
 	for i := range configs {
-		prefix := strings.ToLower(configs[i].PathPrefix)
-
-		if strings.HasPrefix(path, prefix) {
+		if configs[i].matches(path) {
 			if matched == nil || len(configs[i].PathPrefix) > len(matched.PathPrefix) {
 				matched = &configs[i]
 			}
@@ -47,54 +150,62 @@ func matchPathConfig(configs []PathPermissionConfig, path string) *PathPermissio
 	return matched
 }
 
+// matches reports whether the PathPrefix of the configuration matches
+// path, case-insensitively. Public prefixes only match on a segment
+// boundary, so that a public path does not open up its siblings.
+func (c *PathPermissionConfig) matches(path string) bool {
+	path = strings.ToLower(path)
+	prefix := strings.ToLower(c.PathPrefix)
+
+	if !c.Public {
+		return strings.HasPrefix(path, prefix)
+	}
+
+	return path == prefix ||
+		strings.HasSuffix(prefix, "/") && strings.HasPrefix(path, prefix) ||
+		strings.HasPrefix(path, prefix+"/")
+}
+
 // PathPermissionHandler wraps a Connect handler with path-specific permission checking.
 type PathPermissionHandler struct {
-	handler        http.Handler
-	logger         *slog.Logger
-	configurations []PathPermissionConfig
+	handler http.Handler
+	logger  *slog.Logger
+	policy  func() *PathPermissionPolicy
 }
 
 // ServeHTTP implements the http.Handler interface and applies path-based permission checks.
 func (h *PathPermissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	path := r.URL.Path
-
-	// Find the most specific matching path configuration
-	matchedConfig := matchPathConfig(h.configurations, path)
+	h.serveWithPolicy(w, r, h.policy())
+}
 
-	// If no matching configuration, just pass through to the handler
-	if matchedConfig == nil {
-		h.handler.ServeHTTP(w, r)
+// serveWithPolicy applies the path-based permission checks of policy,
+// which the caller loads once per request.
+func (h *PathPermissionHandler) serveWithPolicy(w http.ResponseWriter, r *http.Request, policy *PathPermissionPolicy) {
+	const source = "PathPermissionHandler"
 
-		return
-	}
-
-	// Get auth info from context
 	ctx := r.Context()
+	path := r.URL.Path
 
-	authInfo, err := navigaid.GetAuth(ctx)
-	if err != nil {
-		h.logger.Info("authentication required", "error", err)
-		http.Error(w, "Authentication required", http.StatusUnauthorized)
-
-		return
+	config, err := authorizePath(ctx, h.logger, source, policy, path)
+	if err == nil && config != nil {
+		err = authorizePathUnit(ctx, h.logger, source, path, config, r)
 	}
 
-	// Check org permissions
-	for _, permission := range matchedConfig.Permissions {
-		if !authInfo.Claims.HasPermissionsInOrganisation(permission) {
-			h.logger.Info("permission denied",
-				"path", path,
-				"permission", permission,
-				"user", authInfo.Claims.Subject,
-				"org", authInfo.Claims.Org)
-			http.Error(w, "Permission denied", http.StatusForbidden)
+	var connectErr *connect.Error
 
-			return
-		}
+	switch {
+	case err == nil:
+		// All permissions passed, serve the request
+		h.handler.ServeHTTP(w, r)
+	case !errors.As(err, &connectErr):
+		http.Error(w, "Permission denied", http.StatusForbidden)
+	case connectErr.Code() == connect.CodeUnauthenticated:
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	case connectErr.Code() == connect.CodeInvalidArgument:
+		http.Error(w, "Request does not name a unit", http.StatusBadRequest)
+	default:
+		http.Error(w, "Permission denied: "+connectErr.Message(), http.StatusForbidden)
 	}
-
-	// All permissions passed, serve the request
-	h.handler.ServeHTTP(w, r)
 }
 
 // WithPathPermissionService adds a plain HTTP service with built-in
@@ -104,7 +215,9 @@ func (h *PathPermissionHandler) ServeHTTP(w http.ResponseWriter, r *http.Request
 // on failure), and then checked against the path permission
 // configurations (HTTP 403 on missing permissions). The most specific
 // (longest) matching PathPrefix wins; paths without a matching
-// configuration require authentication but no specific permission.
+// configuration require authentication but no specific permission, and
+// paths of Public configurations require neither. Use
+// WithPathPermissionPolicyService to deny unmatched paths.
 //
 // The service is registered as a plain handler — app-level Connect
 // interceptors (WithInterceptors) are not applied to it.
@@ -142,34 +255,92 @@ func WithPathPermissionService(
 	handler http.Handler,
 	configs []PathPermissionConfig,
 ) Option {
-	return func(a *App) {
-		// Create the handler with path-specific permissions
-		permHandler := &PathPermissionHandler{
-			handler:        handler,
-			logger:         a.logger,
-			configurations: configs,
-		}
+	return WithPathPermissionPolicyService(path, jwks, handler, PathPermissionPolicy{Configs: configs})
+}
 
-		// Authenticate before permission checks.
-		authHandler := navigaid.HTTPMiddleware(a.logger, jwks, permHandler)
+// WithPathPermissionPolicyService is WithPathPermissionService with a
+// PathPermissionPolicy. With DefaultDeny, requests to paths that match
+// no configuration are rejected with HTTP 403. Paths of Public
+// configurations are served without authentication.
+//
+// Example:
+//
+//	app := dindenault.New(logger,
+//	    dindenault.WithPathPermissionPolicyService("/api/", jwks, apiHandler,
+//	        dindenault.PathPermissionPolicy{
+//	            DefaultDeny: true,
+//	            Configs: []dindenault.PathPermissionConfig{
+//	                {PathPrefix: "/api/status", Public: true},
+//	                {PathPrefix: "/api/users", Permissions: []string{"users:read"}},
+//	            },
+//	        },
+//	    ),
+//	)
+func WithPathPermissionPolicyService(
+	path string,
+	jwks *navigaid.JWKS,
+	handler http.Handler,
+	policy PathPermissionPolicy,
+) Option {
+	return func(a *App) {
+		policy.warnAboutProblems(a.logger)
 
-		// Register the service as a plain handler — Connect interceptors
-		// cannot be applied to plain HTTP handlers.
-		WithPlainService(path, authHandler)(a)
+		a.registerPathPermissionService(path, jwks, handler, func() *PathPermissionPolicy { return &policy })
 
 		a.logger.Info("Registered service with path-specific permissions",
 			"path", path,
-			"path_configs", len(configs))
+			"path_configs", len(policy.Configs),
+			"default_deny", policy.DefaultDeny)
 	}
 }
 
+// registerPathPermissionService registers a plain HTTP service that is
+// authenticated and checked against the current policy.
+func (a *App) registerPathPermissionService(
+	path string,
+	jwks *navigaid.JWKS,
+	handler http.Handler,
+	policy func() *PathPermissionPolicy,
+) {
+	// Create the handler with path-specific permissions
+	permHandler := &PathPermissionHandler{
+		handler: handler,
+		logger:  a.logger,
+		policy:  policy,
+	}
+
+	serviceHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Load the policy once, so that a refresh during the request
+		// cannot mix two policies.
+		policy := policy()
+
+		if config := matchPathConfig(policy.Configs, r.URL.Path); config != nil && config.Public {
+			// Served without authentication; the handler records the
+			// public allow decision.
+			permHandler.serveWithPolicy(w, r, policy)
+
+			return
+		}
+
+		// Authenticate before permission checks, except on public paths.
+		navigaid.HTTPMiddleware(a.logger, jwks, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			permHandler.serveWithPolicy(w, r, policy)
+		})).ServeHTTP(w, r)
+	})
+
+	// Register the service as a plain handler — Connect interceptors
+	// cannot be applied to plain HTTP handlers.
+	WithPlainService(path, serviceHandler)(a)
+}
+
 // PathInterceptors creates a Connect interceptor that applies method-level permission checks.
 //
 // This is the recommended way to apply permissions to specific RPC methods.
 // Use this at handler creation time with connect.WithInterceptors.
 //
 // The interceptor checks the RPC method path against configured prefixes and
-// enforces the specified permissions. If no matching configuration is found,
+// enforces the specified permissions, for unary calls and streaming
+// handlers alike. If no matching configuration is found,
 // the request proceeds without additional permission checks.
 //
 // Important: Always apply AuthInterceptors before PathInterceptors to ensure
@@ -201,43 +372,231 @@ func WithPathPermissionService(
 //
 //nolint:ireturn // Returning interface as intended by connect.Interceptor design
 func PathInterceptors(logger *slog.Logger, configs []PathPermissionConfig) connect.Interceptor {
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			// Get the request path
-			path := req.Spec().Procedure
+	return PathPolicyInterceptors(logger, PathPermissionPolicy{Configs: configs})
+}
 
-			// Find the most specific matching path configuration
-			matchedConfig := matchPathConfig(configs, path)
+// PathPolicyInterceptors is PathInterceptors with a
+// PathPermissionPolicy. With DefaultDeny, procedures that match no
+// configuration fail with CodePermissionDenied. Procedures of Public
+// configurations skip the permission checks.
+//
+// Set the policy's Services to have the configurations checked against
+// the service descriptors at startup:
+//
+//	policy := dindenault.PathPermissionPolicy{
+//	    DefaultDeny: true,
+//	    Configs: []dindenault.PathPermissionConfig{
+//	        {PathPrefix: "/service.v1.Service/Ping", Public: true},
+//	        {PathPrefix: "/service.v1.Service/Get", Permissions: []string{"service:read"}},
+//	    },
+//	    Services: []protoreflect.ServiceDescriptor{
+//	        servicev1.File_service_v1_service_proto.Services().ByName("Service"),
+//	    },
+//	}
+//
+//nolint:ireturn // Returning interface as intended by connect.Interceptor design
+func PathPolicyInterceptors(logger *slog.Logger, policy PathPermissionPolicy) connect.Interceptor {
+	policy.warnAboutProblems(logger)
 
-			// If no matching configuration, just pass through to the handler
-			if matchedConfig == nil {
-				return next(ctx, req)
-			}
+	return &pathPolicyInterceptor{logger: logger, policy: func() *PathPermissionPolicy { return &policy }}
+}
 
-			// Get auth info from context
-			authInfo, err := navigaid.GetAuth(ctx)
-			if err != nil {
-				logger.Info("authentication required", "error", err)
+// pathInterceptorsSource is the audit event source of PathInterceptors.
+const pathInterceptorsSource = "PathInterceptors"
 
-				return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
-			}
+type pathPolicyInterceptor struct {
+	logger *slog.Logger
+	// policy returns the current policy.
+	policy func() *PathPermissionPolicy
+}
+
+// authorizePath checks a request against a policy and records the
+// decision. If the matched configuration has a Unit it is returned
+// instead, and its permissions must be checked against the request
+// with authorizePathUnit.
+func authorizePath(
+	ctx context.Context, logger *slog.Logger, source string, policy *PathPermissionPolicy, path string,
+) (*PathPermissionConfig, error) {
+	event := navigaid.AuditEvent{Source: source, Procedure: path}
+
+	// Find the most specific matching path configuration
+	matchedConfig := matchPathConfig(policy.Configs, path)
+
+	// If no matching configuration, deny or pass through to the
+	// handler, depending on the policy
+	if matchedConfig == nil {
+		event.Reason = "no path permission configuration"
+
+		if policy.DefaultDeny {
+			logger.InfoContext(ctx, "permission denied: no path permission configuration", "path", path)
+			recordDecision(ctx, false, event)
+
+			return nil, connect.NewError(connect.CodePermissionDenied,
+				errors.New("no permission configuration for "+path))
+		}
+
+		recordDecision(ctx, true, event)
+
+		return nil, nil
+	}
+
+	event.Permissions = matchedConfig.requiredPermissions()
+
+	if matchedConfig.Public {
+		event.Reason = "public"
+		recordDecision(ctx, true, event)
+
+		return nil, nil
+	}
+
+	// Get auth info from context
+	authInfo, err := navigaid.GetAuth(ctx)
+	if err != nil {
+		logger.InfoContext(ctx, "authentication required", "error", err)
+
+		event.Reason = "not authenticated"
+		recordDecision(ctx, false, event)
+
+		return nil, connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
+	}
+
+	if matchedConfig.Unit != nil {
+		return matchedConfig, nil
+	}
+
+	// Check org permissions
+	for _, permission := range matchedConfig.Permissions {
+		if !authInfo.Claims.HasPermissionsInOrganisation(permission) {
+			logger.InfoContext(ctx, "permission denied",
+				"path", path,
+				"permission", permission,
+				"user", authInfo.Claims.Subject,
+				"org", authInfo.Claims.Org)
+
+			event.Reason = "lacks permission " + permission
+			recordDecision(ctx, false, event)
+
+			return nil, connect.NewError(connect.CodePermissionDenied,
+				errors.New("missing required permission: "+permission))
+		}
+	}
+
+	if err := checkRequirement(ctx, logger, event, matchedConfig, navigaid.Caller{Claims: authInfo.Claims}); err != nil {
+		return nil, err
+	}
+
+	// All permissions passed, continue with the request
+	return nil, nil
+}
+
+// authorizePathUnit checks the permissions of a configuration with a
+// Unit extractor in the unit of a request, and records the decision.
+// msg is the request message, or the *http.Request.
+func authorizePathUnit(
+	ctx context.Context, logger *slog.Logger, source, path string, config *PathPermissionConfig, msg any,
+) error {
+	event := navigaid.AuditEvent{Source: source, Procedure: path, Permissions: config.requiredPermissions()}
+
+	authInfo, err := navigaid.GetAuth(ctx)
+	if err != nil {
+		event.Reason = "not authenticated"
+		recordDecision(ctx, false, event)
+
+		return connect.NewError(connect.CodeUnauthenticated, errors.New("authentication required"))
+	}
+
+	unit, unitErr := extractUnit(ctx, logger, config.Unit, msg)
+	if unitErr != nil {
+		event.Reason = unitErr.Message()
+		recordDecision(ctx, false, event)
+
+		return unitErr
+	}
+
+	event.Unit = unit
+
+	if !authInfo.Claims.HasPermissionsInUnit(unit, config.Permissions...) {
+		logger.InfoContext(ctx, "permission denied for unit",
+			"path", path,
+			"unit", unit,
+			"permissions", config.Permissions,
+			"user", authInfo.Claims.Subject,
+			"org", authInfo.Claims.Org)
+
+		event.Reason = "lacks permissions in unit " + unit
+		recordDecision(ctx, false, event)
+
+		return unitPermissionDenied(unit, config.Permissions)
+	}
+
+	return checkRequirement(ctx, logger, event, config, navigaid.Caller{Claims: authInfo.Claims, Unit: unit})
+}
 
-			// Check org permissions
-			for _, permission := range matchedConfig.Permissions {
-				if !authInfo.Claims.HasPermissionsInOrganisation(permission) {
-					logger.Info("permission denied",
-						"path", path,
-						"permission", permission,
-						"user", authInfo.Claims.Subject,
-						"org", authInfo.Claims.Org)
-
-					return nil, connect.NewError(connect.CodePermissionDenied,
-						errors.New("missing required permission: "+permission))
-				}
+// checkRequirement checks the Require expression of a configuration
+// whose permissions have passed, and records the decision.
+func checkRequirement(
+	ctx context.Context, logger *slog.Logger, event navigaid.AuditEvent, config *PathPermissionConfig, caller navigaid.Caller,
+) error {
+	var requirementErr *navigaid.RequirementError
+
+	if !errors.As(navigaid.CheckRequirement(config.Require, caller), &requirementErr) {
+		event.Reason = "has required permissions"
+		recordDecision(ctx, true, event)
+
+		return nil
+	}
+
+	logger.InfoContext(ctx, "permission denied",
+		"path", event.Procedure,
+		"reason", requirementErr.Error(),
+		"user", caller.Claims.Subject,
+		"org", caller.Claims.Org)
+
+	event.Reason = requirementErr.Reason
+	recordDecision(ctx, false, event)
+
+	return connect.NewError(connect.CodePermissionDenied, requirementErr)
+}
+
+func (i *pathPolicyInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		path := req.Spec().Procedure
+
+		config, err := authorizePath(ctx, i.logger, pathInterceptorsSource, i.policy(), path)
+		if err != nil {
+			return nil, err
+		}
+
+		if config != nil {
+			if err := authorizePathUnit(ctx, i.logger, pathInterceptorsSource, path, config, req.Any()); err != nil {
+				return nil, err
 			}
+		}
 
-			// All permissions passed, continue with the request
-			return next(ctx, req)
+		return next(ctx, req)
+	}
+}
+
+func (i *pathPolicyInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *pathPolicyInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		path := conn.Spec().Procedure
+
+		config, err := authorizePath(ctx, i.logger, pathInterceptorsSource, i.policy(), path)
+		if err != nil {
+			return err
 		}
-	})
+
+		if config == nil {
+			return next(ctx, conn)
+		}
+
+		// The unit is read from every message the handler receives.
+		return serveUnitChecked(ctx, next, conn, func(msg any) error {
+			return authorizePathUnit(ctx, i.logger, pathInterceptorsSource, path, config, msg)
+		})
+	}
 }
@@ -0,0 +1,228 @@
+package dindenault_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// readerJWKS accepts any token, granting the "reader" token the
+// articles:read permission.
+func readerJWKS() *navigaid.JWKS {
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(token string) (navigaid.Claims, error) {
+		claims := navigaid.Claims{Org: "acme"}
+		if token == "reader" {
+			claims.Permissions.Org = []string{"articles:read"}
+		}
+
+		return claims, nil
+	})
+
+	return jwks
+}
+
+func TestPathPolicyInterceptors(t *testing.T) {
+	policy := dindenault.PathPermissionPolicy{
+		DefaultDeny: true,
+		Configs: []dindenault.PathPermissionConfig{
+			{PathPrefix: "/test.v1.ArticleService/Get", Permissions: []string{"articles:read"}},
+			{PathPrefix: "/test.v1.ArticleService/Ping", Public: true},
+		},
+	}
+
+	interceptors := connect.WithInterceptors(
+		dindenault.PathPolicyInterceptors(slog.Default(), policy),
+	)
+
+	mux := http.NewServeMux()
+
+	for _, method := range []string{"Get", "Ping", "Delete"} {
+		procedure := "/test.v1.ArticleService/" + method
+		mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+			func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			}, interceptors))
+	}
+
+	server := httptest.NewServer(navigaid.HTTPMiddleware(slog.Default(), readerJWKS(), mux))
+	defer server.Close()
+
+	call := func(method string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+			server.URL+"/test.v1.ArticleService/"+method)
+
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set("Authorization", "Bearer reader")
+
+		_, err := client.CallUnary(context.Background(), req)
+
+		return err //nolint:wrapcheck // test helper
+	}
+
+	if err := call("Get"); err != nil {
+		t.Errorf("Expected configured method to be allowed, got %v", err)
+	}
+
+	if err := call("Ping"); err != nil {
+		t.Errorf("Expected public method to be allowed, got %v", err)
+	}
+
+	if err := call("Delete"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("Expected unconfigured method to be denied, got %v", err)
+	}
+}
+
+func TestPathInterceptorsRequire(t *testing.T) {
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(token string) (navigaid.Claims, error) {
+		claims := navigaid.Claims{Org: "acme"}
+
+		switch token {
+		case "editor":
+			claims.Permissions.Org = []string{"articles:edit"}
+		case "admin":
+			claims.Groups = []string{"admins"}
+		case "suspended-editor":
+			claims.Permissions.Org = []string{"articles:edit"}
+			claims.Groups = []string{"suspended"}
+		}
+
+		return claims, nil
+	})
+
+	const procedure = "/test.v1.ArticleService/Update"
+
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+		func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		},
+		connect.WithInterceptors(dindenault.PathInterceptors(slog.Default(), []dindenault.PathPermissionConfig{
+			{
+				PathPrefix: procedure,
+				Require: navigaid.MustParseRequirement(
+					"(articles:edit or group(admins)) and not group(suspended)"),
+			},
+		}))))
+
+	server := httptest.NewServer(navigaid.HTTPMiddleware(slog.Default(), jwks, mux))
+	defer server.Close()
+
+	tests := []struct {
+		token string
+		code  connect.Code
+	}{
+		{token: "editor"},
+		{token: "admin"},
+		{token: "suspended-editor", code: connect.CodePermissionDenied},
+		{token: "nobody", code: connect.CodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+procedure)
+
+			req := connect.NewRequest(&emptypb.Empty{})
+			req.Header().Set("Authorization", "Bearer "+tt.token)
+
+			_, err := client.CallUnary(context.Background(), req)
+			if tt.code == 0 && err != nil {
+				t.Fatalf("Expected success, got %v", err)
+			}
+
+			if tt.code != 0 && connect.CodeOf(err) != tt.code {
+				t.Errorf("Expected %v, got %v", tt.code, err)
+			}
+		})
+	}
+}
+
+func TestWithPathPermissionPolicyService(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithPathPermissionPolicyService("/api/", readerJWKS(),
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			dindenault.PathPermissionPolicy{
+				DefaultDeny: true,
+				Configs: []dindenault.PathPermissionConfig{
+					{PathPrefix: "/api/articles", Permissions: []string{"articles:read"}},
+					{PathPrefix: "/api/status", Public: true},
+					{PathPrefix: "/api/drafts", Require: navigaid.MustParseRequirement("articles:write or articles:read")},
+				},
+			},
+		),
+	)
+
+	handler := app.HTTPHandler()
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{path: "/api/articles/1", token: "reader", status: http.StatusNoContent},
+		{path: "/api/articles/1", token: "other", status: http.StatusForbidden},
+		{path: "/api/articles/1", status: http.StatusUnauthorized},
+		{path: "/api/status", status: http.StatusNoContent},
+		{path: "/api/status/db", status: http.StatusNoContent},
+		// Siblings sharing the string prefix of a public path are not public.
+		{path: "/api/statusX", status: http.StatusUnauthorized},
+		{path: "/api/status-admin", status: http.StatusUnauthorized},
+		{path: "/api/status-admin", token: "reader", status: http.StatusForbidden},
+		{path: "/api/admin", token: "reader", status: http.StatusForbidden},
+		{path: "/api/drafts", token: "reader", status: http.StatusNoContent},
+		{path: "/api/drafts", token: "other", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+"/"+tt.token, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
+
+func TestPathPermissionPolicyCheck(t *testing.T) {
+	policy := dindenault.PathPermissionPolicy{
+		DefaultDeny: true,
+		Configs: []dindenault.PathPermissionConfig{
+			{PathPrefix: "/test.v1.ArticleService/Get", Permissions: []string{"articles:read"}},
+			{PathPrefix: "/test.v1.ArticleService/List", Permissions: []string{"articles:read"}},
+			{PathPrefix: "/test.v1.ArticleService/Ping", Public: true, Permissions: []string{"articles:read"}},
+			{PathPrefix: "/test.v1.ArticleService/Watch"},
+			{PathPrefix: "/test.v1.ArticleService/Udpate", Permissions: []string{"articles:write"}},
+		},
+	}
+
+	problems := policy.Check(articleService(t, "test.v1"))
+
+	expected := []string{
+		"/test.v1.ArticleService/Delete matches no configuration and is denied",
+		"prefix /test.v1.ArticleService/Ping is public but lists permissions",
+		"prefix /test.v1.ArticleService/Udpate matches no procedure",
+	}
+
+	if !slices.Equal(problems, expected) {
+		t.Errorf("Expected problems\n%q\ngot\n%q", expected, problems)
+	}
+}
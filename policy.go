@@ -0,0 +1,352 @@
+package dindenault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"gopkg.in/yaml.v3"
+
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// PolicySource loads a path permission policy document, in the format
+// read by ParsePathPermissionPolicy. Implement it to keep policies in
+// S3, SSM Parameter Store or similar.
+type PolicySource interface {
+	LoadPolicy(ctx context.Context) ([]byte, error)
+}
+
+// PolicySourceFunc adapts a function to the PolicySource interface:
+//
+//	source := dindenault.PolicySourceFunc(func(ctx context.Context) ([]byte, error) {
+//	    out, err := ssmClient.GetParameter(ctx, &ssm.GetParameterInput{Name: aws.String("/svc/policy")})
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//
+//	    return []byte(aws.ToString(out.Parameter.Value)), nil
+//	})
+type PolicySourceFunc func(ctx context.Context) ([]byte, error)
+
+// LoadPolicy calls f(ctx).
+func (f PolicySourceFunc) LoadPolicy(ctx context.Context) ([]byte, error) {
+	return f(ctx)
+}
+
+// FilePolicySource reads the policy from a file.
+//
+//nolint:ireturn // Returning interface as intended by PolicySource design
+func FilePolicySource(path string) PolicySource {
+	return PolicySourceFunc(func(context.Context) ([]byte, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read policy: %w", err)
+		}
+
+		return data, nil
+	})
+}
+
+// EnvPolicySource reads the policy from an environment variable.
+//
+//nolint:ireturn // Returning interface as intended by PolicySource design
+func EnvPolicySource(name string) PolicySource {
+	return PolicySourceFunc(func(context.Context) ([]byte, error) {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", name)
+		}
+
+		return []byte(value), nil
+	})
+}
+
+// policyDocument is the external representation of a
+// PathPermissionPolicy.
+type policyDocument struct {
+	DefaultDeny bool         `json:"default_deny" yaml:"default_deny"`
+	Rules       []policyRule `json:"rules"        yaml:"rules"`
+}
+
+type policyRule struct {
+	Path        string   `json:"path"        yaml:"path"`
+	Permissions []string `json:"permissions" yaml:"permissions"`
+	Public      bool     `json:"public"      yaml:"public"`
+	UnitField   string   `json:"unit_field"  yaml:"unit_field"`
+	Require     string   `json:"require"     yaml:"require"`
+}
+
+// ParsePathPermissionPolicy parses and validates a policy document in
+// JSON or YAML:
+//
+//	default_deny: true
+//	rules:
+//	  - path: /article.v1.ArticleService/Ping
+//	    public: true
+//	  - path: /article.v1.ArticleService/Get
+//	    permissions: [articles:read]
+//	  - path: /article.v1.ArticleService/Publish
+//	    permissions: [articles:publish]
+//	    unit_field: unit
+//	  - path: /article.v1.ArticleService/Update
+//	    require: "(articles:edit or group(admins)) and not group(suspended)"
+//
+// unit_field is read with UnitField and require with
+// navigaid.ParseRequirement. Unknown fields, rules without a path,
+// duplicate paths, public rules with permissions, and invalid unit
+// fields or requirements are errors.
+func ParsePathPermissionPolicy(data []byte) (PathPermissionPolicy, error) {
+	var doc policyDocument
+
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(trimmed))
+		decoder.DisallowUnknownFields()
+
+		if err := decoder.Decode(&doc); err != nil {
+			return PathPermissionPolicy{}, fmt.Errorf("invalid policy JSON: %w", err)
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(trimmed))
+		decoder.KnownFields(true)
+
+		if err := decoder.Decode(&doc); err != nil {
+			return PathPermissionPolicy{}, fmt.Errorf("invalid policy YAML: %w", err)
+		}
+	}
+
+	policy := PathPermissionPolicy{DefaultDeny: doc.DefaultDeny}
+
+	var problems []string
+
+	seen := make(map[string]bool)
+
+	for i, rule := range doc.Rules {
+		config, err := rule.config()
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("rule %d (%s): %v", i+1, rule.Path, err))
+
+			continue
+		}
+
+		if seen[strings.ToLower(rule.Path)] {
+			problems = append(problems, fmt.Sprintf("rule %d (%s): duplicate path", i+1, rule.Path))
+
+			continue
+		}
+
+		seen[strings.ToLower(rule.Path)] = true
+
+		policy.Configs = append(policy.Configs, config)
+	}
+
+	if len(problems) > 0 {
+		return PathPermissionPolicy{}, fmt.Errorf("invalid path permission policy:\n  %s",
+			strings.Join(problems, "\n  "))
+	}
+
+	return policy, nil
+}
+
+// config converts a rule to a PathPermissionConfig.
+func (r policyRule) config() (PathPermissionConfig, error) {
+	config := PathPermissionConfig{
+		PathPrefix:  r.Path,
+		Permissions: r.Permissions,
+		Public:      r.Public,
+	}
+
+	if r.Path == "" {
+		return PathPermissionConfig{}, errors.New("missing path")
+	}
+
+	if r.Public && (len(r.Permissions) > 0 || r.UnitField != "" || r.Require != "") {
+		return PathPermissionConfig{}, errors.New("public rules cannot require permissions")
+	}
+
+	if r.UnitField != "" {
+		if err := validateUnitFieldPath(r.UnitField); err != nil {
+			return PathPermissionConfig{}, err
+		}
+
+		config.Unit = UnitField(r.UnitField)
+	}
+
+	if r.Require != "" {
+		requirement, err := navigaid.ParseRequirement(r.Require)
+		if err != nil {
+			return PathPermissionConfig{}, err //nolint:wrapcheck // already names the requirement
+		}
+
+		config.Require = requirement
+	}
+
+	return config, nil
+}
+
+// validateUnitFieldPath checks the syntax of a unit field path; the
+// fields themselves are resolved against the request message.
+func validateUnitFieldPath(path string) error {
+	for name := range strings.SplitSeq(path, ".") {
+		if !protoreflect.Name(name).IsValid() {
+			return fmt.Errorf("invalid unit field %q", path)
+		}
+	}
+
+	return nil
+}
+
+// PolicyLoaderOption configures a PolicyLoader.
+type PolicyLoaderOption func(*PolicyLoader)
+
+// WithPolicyServices compares every loaded policy with the methods of
+// the services, like PathPermissionPolicy.Services, and logs the
+// problems found as warnings.
+func WithPolicyServices(services ...protoreflect.ServiceDescriptor) PolicyLoaderOption {
+	return func(l *PolicyLoader) {
+		l.services = services
+	}
+}
+
+// PolicyLoader holds a PathPermissionPolicy loaded from a PolicySource
+// and replaces it when it is refreshed. Requests always see a complete
+// policy: a new one is parsed and validated before it is swapped in,
+// and a policy that fails to load or validate leaves the current one in
+// place.
+//
+// Use it with PathLoaderInterceptors and
+// WithPathPermissionLoaderService.
+type PolicyLoader struct {
+	source   PolicySource
+	logger   *slog.Logger
+	services []protoreflect.ServiceDescriptor
+
+	policy atomic.Pointer[PathPermissionPolicy]
+
+	// refreshMu serialises refreshes, so that a slow load cannot
+	// replace a newer policy.
+	refreshMu sync.Mutex
+}
+
+// NewPolicyLoader creates a PolicyLoader and loads the initial policy,
+// returning an error if it cannot be loaded.
+//
+// Example:
+//
+//	loader, err := dindenault.NewPolicyLoader(ctx, logger,
+//	    dindenault.FilePolicySource("/etc/service/policy.yaml"))
+//	if err != nil {
+//	    return err
+//	}
+//
+//	go loader.Run(ctx, time.Minute)
+func NewPolicyLoader(
+	ctx context.Context,
+	logger *slog.Logger,
+	source PolicySource,
+	options ...PolicyLoaderOption,
+) (*PolicyLoader, error) {
+	l := &PolicyLoader{
+		source: source,
+		logger: logger,
+	}
+
+	for _, option := range options {
+		option(l)
+	}
+
+	if err := l.Refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Policy returns the current policy.
+func (l *PolicyLoader) Policy() PathPermissionPolicy {
+	return *l.policy.Load()
+}
+
+// Refresh loads the policy from the source and swaps it in if it is
+// valid. On error the current policy is kept.
+func (l *PolicyLoader) Refresh(ctx context.Context) error {
+	l.refreshMu.Lock()
+	defer l.refreshMu.Unlock()
+
+	data, err := l.source.LoadPolicy(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load path permission policy: %w", err)
+	}
+
+	policy, err := ParsePathPermissionPolicy(data)
+	if err != nil {
+		return err
+	}
+
+	policy.Services = l.services
+	policy.warnAboutProblems(l.logger)
+
+	l.policy.Store(&policy)
+
+	l.logger.Info("Loaded path permission policy",
+		"path_configs", len(policy.Configs),
+		"default_deny", policy.DefaultDeny)
+
+	return nil
+}
+
+// Run refreshes the policy at the given interval until ctx is done,
+// logging failures. Start it in a goroutine. In Lambda the timer only
+// runs while the execution environment is handling invocations, so the
+// policy is refreshed at most once per interval of activity.
+func (l *PolicyLoader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := l.Refresh(ctx); err != nil {
+				l.logger.ErrorContext(ctx, "Failed to refresh path permission policy; keeping the current policy",
+					"error", err)
+			}
+		}
+	}
+}
+
+// PathLoaderInterceptors is PathPolicyInterceptors with the current
+// policy of a PolicyLoader, so that refreshed policies apply to the
+// following calls.
+//
+//nolint:ireturn // Returning interface as intended by connect.Interceptor design
+func PathLoaderInterceptors(logger *slog.Logger, loader *PolicyLoader) connect.Interceptor {
+	return &pathPolicyInterceptor{logger: logger, policy: loader.policy.Load}
+}
+
+// WithPathPermissionLoaderService is WithPathPermissionPolicyService
+// with the current policy of a PolicyLoader.
+func WithPathPermissionLoaderService(
+	path string,
+	jwks *navigaid.JWKS,
+	handler http.Handler,
+	loader *PolicyLoader,
+) Option {
+	return func(a *App) {
+		a.registerPathPermissionService(path, jwks, handler, loader.policy.Load)
+
+		a.logger.Info("Registered service with reloadable path permissions", "path", path)
+	}
+}
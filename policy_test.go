@@ -0,0 +1,295 @@
+package dindenault_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+func TestParsePathPermissionPolicy(t *testing.T) {
+	documents := map[string]string{
+		"yaml": `
+default_deny: true
+rules:
+  - path: /test.v1.ArticleService/Ping
+    public: true
+  - path: /test.v1.ArticleService/Get
+    permissions: [articles:read]
+    unit_field: article.unit
+  - path: /test.v1.ArticleService/Update
+    require: "articles:edit or group(admins)"
+`,
+		"json": `{
+  "default_deny": true,
+  "rules": [
+    {"path": "/test.v1.ArticleService/Ping", "public": true},
+    {"path": "/test.v1.ArticleService/Get", "permissions": ["articles:read"], "unit_field": "article.unit"},
+    {"path": "/test.v1.ArticleService/Update", "require": "articles:edit or group(admins)"}
+  ]
+}`,
+	}
+
+	for format, document := range documents {
+		t.Run(format, func(t *testing.T) {
+			policy, err := dindenault.ParsePathPermissionPolicy([]byte(document))
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+
+			if !policy.DefaultDeny || len(policy.Configs) != 3 {
+				t.Fatalf("Unexpected policy %+v", policy)
+			}
+
+			if !policy.Configs[0].Public {
+				t.Error("Expected the first rule to be public")
+			}
+
+			if policy.Configs[1].Permissions[0] != "articles:read" || policy.Configs[1].Unit == nil {
+				t.Errorf("Unexpected unit rule %+v", policy.Configs[1])
+			}
+
+			if policy.Configs[2].Require.String() != "articles:edit or group(admins)" {
+				t.Errorf("Unexpected requirement %v", policy.Configs[2].Require)
+			}
+		})
+	}
+}
+
+func TestParsePathPermissionPolicyErrors(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":     "rules:\n  - path: /a\n    permission: [a]\n",
+		"missing path":      "rules:\n  - permissions: [a]\n",
+		"public with perms": "rules:\n  - path: /a\n    public: true\n    permissions: [a]\n",
+		"duplicate path":    "rules:\n  - path: /a\n  - path: /A\n",
+		"bad requirement":   "rules:\n  - path: /a\n    require: \"a and\"\n",
+		"bad unit field":    "rules:\n  - path: /a\n    unit_field: \"a..b\"\n",
+		"unknown JSON":      `{"rules": [], "deny": true}`,
+		"empty":             "",
+	}
+
+	for name, document := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := dindenault.ParsePathPermissionPolicy([]byte(document)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestEnvPolicySource(t *testing.T) {
+	t.Setenv("TEST_PATH_POLICY", `{"rules": [{"path": "/a", "public": true}]}`)
+
+	loader, err := dindenault.NewPolicyLoader(context.Background(), slog.Default(),
+		dindenault.EnvPolicySource("TEST_PATH_POLICY"))
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	if configs := loader.Policy().Configs; len(configs) != 1 || !configs[0].Public {
+		t.Errorf("Unexpected policy %+v", loader.Policy())
+	}
+
+	if _, err := dindenault.NewPolicyLoader(context.Background(), slog.Default(),
+		dindenault.EnvPolicySource("TEST_PATH_POLICY_UNSET")); err == nil {
+		t.Error("Expected an error for an unset variable")
+	}
+}
+
+func TestPolicyLoader(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "policy.yaml")
+
+	write := func(document string) {
+		if err := os.WriteFile(file, []byte(document), 0o600); err != nil {
+			t.Fatalf("Failed to write policy: %v", err)
+		}
+	}
+
+	write(`
+default_deny: true
+rules:
+  - path: /test.v1.ArticleService/Get
+    permissions: [articles:read]
+`)
+
+	loader, err := dindenault.NewPolicyLoader(context.Background(), slog.Default(),
+		dindenault.FilePolicySource(file))
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	mux := http.NewServeMux()
+
+	for _, method := range []string{"Get", "Delete"} {
+		procedure := "/test.v1.ArticleService/" + method
+		mux.Handle(procedure, connect.NewUnaryHandler(procedure,
+			func(context.Context, *connect.Request[emptypb.Empty]) (*connect.Response[emptypb.Empty], error) {
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			}, connect.WithInterceptors(dindenault.PathLoaderInterceptors(slog.Default(), loader))))
+	}
+
+	server := httptest.NewServer(navigaid.HTTPMiddleware(slog.Default(), readerJWKS(), mux))
+	defer server.Close()
+
+	call := func(method string) error {
+		client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(),
+			server.URL+"/test.v1.ArticleService/"+method)
+
+		req := connect.NewRequest(&emptypb.Empty{})
+		req.Header().Set("Authorization", "Bearer reader")
+
+		_, err := client.CallUnary(context.Background(), req)
+
+		return err //nolint:wrapcheck // test helper
+	}
+
+	if err := call("Get"); err != nil {
+		t.Errorf("Expected Get to be allowed, got %v", err)
+	}
+
+	if err := call("Delete"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("Expected Delete to be denied, got %v", err)
+	}
+
+	write(`
+default_deny: true
+rules:
+  - path: /test.v1.ArticleService/Get
+    permissions: [articles:write]
+  - path: /test.v1.ArticleService/Delete
+    permissions: [articles:read]
+`)
+
+	if err := loader.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh policy: %v", err)
+	}
+
+	if err := call("Get"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("Expected Get to be denied after refresh, got %v", err)
+	}
+
+	if err := call("Delete"); err != nil {
+		t.Errorf("Expected Delete to be allowed after refresh, got %v", err)
+	}
+
+	// An invalid policy is rejected and the current one kept.
+	write("rules:\n  - permissions: [articles:read]\n")
+
+	err = loader.Refresh(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "missing path") {
+		t.Fatalf("Expected the invalid policy to be rejected, got %v", err)
+	}
+
+	if err := call("Delete"); err != nil {
+		t.Errorf("Expected the previous policy to be kept, got %v", err)
+	}
+}
+
+func TestWithPathPermissionLoaderService(t *testing.T) {
+	document := `{"default_deny": true, "rules": [{"path": "/api/articles", "permissions": ["articles:read"]}]}`
+
+	loader, err := dindenault.NewPolicyLoader(context.Background(), slog.Default(),
+		dindenault.PolicySourceFunc(func(context.Context) ([]byte, error) {
+			return []byte(document), nil
+		}))
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithPathPermissionLoaderService("/api/", readerJWKS(),
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			loader,
+		),
+	)
+
+	handler := app.HTTPHandler()
+
+	status := func(path string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer reader")
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	if code := status("/api/status"); code != http.StatusForbidden {
+		t.Errorf("Expected unconfigured path to be denied, got %d", code)
+	}
+
+	document = `{"rules": [{"path": "/api/status", "public": true}]}`
+
+	if err := loader.Refresh(context.Background()); err != nil {
+		t.Fatalf("Failed to refresh policy: %v", err)
+	}
+
+	if code := status("/api/status"); code != http.StatusNoContent {
+		t.Errorf("Expected public path to be served after refresh, got %d", code)
+	}
+}
+
+func TestWithPathPermissionLoaderServiceRefreshDuringRequest(t *testing.T) {
+	document := `{"rules": [{"path": "/api/articles", "permissions": ["articles:read"]}]}`
+
+	loader, err := dindenault.NewPolicyLoader(context.Background(), slog.Default(),
+		dindenault.PolicySourceFunc(func(context.Context) ([]byte, error) {
+			return []byte(document), nil
+		}))
+	if err != nil {
+		t.Fatalf("Failed to load policy: %v", err)
+	}
+
+	// The policy is replaced while the request is being authenticated,
+	// between the public path check and the permission check.
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(string) (navigaid.Claims, error) {
+		document = `{"rules": [{"path": "/api/articles", "permissions": ["articles:write"]}]}`
+
+		if err := loader.Refresh(context.Background()); err != nil {
+			t.Errorf("Failed to refresh policy: %v", err)
+		}
+
+		return navigaid.Claims{Org: "acme", Permissions: navigaid.PermissionsClaim{Org: []string{"articles:read"}}}, nil
+	})
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithPathPermissionLoaderService("/api/", jwks,
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			loader,
+		),
+	)
+
+	status := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/articles", nil)
+		req.Header.Set("Authorization", "Bearer reader")
+
+		rec := httptest.NewRecorder()
+		app.HTTPHandler().ServeHTTP(rec, req)
+
+		return rec.Code
+	}
+
+	if code := status(); code != http.StatusNoContent {
+		t.Errorf("Expected the request to be checked against the policy it started with, got %d", code)
+	}
+
+	if code := status(); code != http.StatusForbidden {
+		t.Errorf("Expected the next request to use the refreshed policy, got %d", code)
+	}
+}
@@ -0,0 +1,59 @@
+package dindenault
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+)
+
+// rpcErrorWriter writes errors in the wire format of Connect, gRPC and
+// gRPC-Web requests. The Connect protocol header is required, so that
+// plain HTTP requests (such as a browser GET) are not mistaken for
+// Connect unary calls.
+var rpcErrorWriter = connect.NewErrorWriter(connect.WithRequireConnectProtocolHeader())
+
+// isRPCRequest reports whether r uses the Connect, gRPC or gRPC-Web
+// protocol.
+func isRPCRequest(r *http.Request) bool {
+	return rpcErrorWriter.IsSupported(r)
+}
+
+// writeError answers r with an error in the caller's protocol: a
+// Connect, gRPC or gRPC-Web error with the given code for RPC requests,
+// and a plain HTTP error with the given status for everything else. The
+// request ID is echoed like for regular responses.
+func writeError(w http.ResponseWriter, r *http.Request, code connect.Code, status int, message string) {
+	setRequestIDHeader(w, r)
+
+	if isRPCRequest(r) {
+		_ = rpcErrorWriter.Write(w, r, connect.NewError(code, errors.New(message)))
+
+		return
+	}
+
+	http.Error(w, message, status)
+}
+
+// problem is an RFC 9457 problem details object.
+type problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// writeProblem writes an application/problem+json response.
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
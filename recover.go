@@ -0,0 +1,121 @@
+package dindenault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"connectrpc.com/connect"
+)
+
+// PanicHandler writes the response for a request whose handler
+// panicked. recovered is the value passed to panic. The panic has
+// already been logged and reported when the handler is called.
+type PanicHandler func(w http.ResponseWriter, r *http.Request, recovered any)
+
+// PanicReporter can be implemented by a TelemetryProvider to be told
+// about panics in registered handlers, for example to mark the current
+// trace as failed.
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, recovered any, stack []byte)
+}
+
+// WithPanicHandler customises the response written when a registered
+// handler panics. Panics are always recovered, logged with their stack
+// trace and reported to the telemetry provider if it implements
+// PanicReporter; by default the client then receives a Connect
+// internal error for RPC requests and an HTTP 500 for other requests.
+//
+// The handler is not called if the response was already started before
+// the panic, since the status code can no longer be changed.
+func WithPanicHandler(handler PanicHandler) Option {
+	return func(a *App) {
+		a.panicHandler = handler
+	}
+}
+
+// defaultPanicHandler answers with an internal error in the caller's
+// protocol. Panic details are never returned to clients.
+func defaultPanicHandler(w http.ResponseWriter, r *http.Request, _ any) {
+	writeError(w, r, connect.CodeInternal, http.StatusInternalServerError, internalServerErrorBody)
+}
+
+// recoverPanics wraps handler so that a panic is logged, reported and
+// turned into an error response instead of crashing the invocation.
+func (a *App) recoverPanics(path string, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rw := &panicResponseWriter{ResponseWriter: w}
+
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			// http.ErrAbortHandler is the conventional way for a
+			// handler to abort a response; it is not a failure.
+			if err, ok := recovered.(error); ok && errors.Is(err, http.ErrAbortHandler) {
+				return
+			}
+
+			stack := debug.Stack()
+
+			a.logger.ErrorContext(r.Context(), "Handler panicked",
+				"registration", path,
+				"path", r.URL.Path,
+				"panic", fmt.Sprint(recovered),
+				"stack", string(stack))
+
+			if reporter, ok := a.telemetryProvider.(PanicReporter); ok {
+				reporter.ReportPanic(r.Context(), recovered, stack)
+			}
+
+			if rw.started {
+				return
+			}
+
+			panicHandler := a.panicHandler
+			if panicHandler == nil {
+				panicHandler = defaultPanicHandler
+			}
+
+			panicHandler(w, r, recovered)
+		}()
+
+		handler.ServeHTTP(rw, r)
+	})
+}
+
+// panicResponseWriter records whether a response has been started, so
+// that recoverPanics does not write a second status line.
+type panicResponseWriter struct {
+	http.ResponseWriter
+
+	started bool
+}
+
+func (w *panicResponseWriter) WriteHeader(status int) {
+	w.started = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *panicResponseWriter) Write(body []byte) (int, error) {
+	w.started = true
+
+	return w.ResponseWriter.Write(body) //nolint:wrapcheck // transparent wrapper
+}
+
+// Flush implements http.Flusher, which Connect needs for streaming.
+func (w *panicResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.started = true
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *panicResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,137 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/navigacontentlab/dindenault"
+)
+
+// panicReportingTelemetry is a TelemetryProvider that records panics.
+type panicReportingTelemetry struct {
+	dindenault.NoopTelemetry
+
+	recovered any
+	stack     []byte
+}
+
+func (p *panicReportingTelemetry) ReportPanic(_ context.Context, recovered any, stack []byte) {
+	p.recovered = recovered
+	p.stack = stack
+}
+
+func TestPanicRecovery(t *testing.T) {
+	panicking := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})
+
+	invoke := func(t *testing.T, app *dindenault.App, headers map[string]string) events.ALBTargetGroupResponse {
+		t.Helper()
+
+		resp, err := app.Handle()(context.Background(), events.ALBTargetGroupRequest{
+			HTTPMethod: http.MethodPost,
+			Path:       "/api/method",
+			Headers:    headers,
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		return resp
+	}
+
+	t.Run("logs and returns 500 for plain requests", func(t *testing.T) {
+		var logs bytes.Buffer
+
+		app := dindenault.New(slog.New(slog.NewTextHandler(&logs, nil)),
+			dindenault.WithService("/api/", panicking),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("Expected status 500, got %d", resp.StatusCode)
+		}
+
+		if strings.Contains(resp.Body, "boom") {
+			t.Error("Panic value must not be returned to the client")
+		}
+
+		if !strings.Contains(logs.String(), "boom") || !strings.Contains(logs.String(), "recover_test.go") {
+			t.Errorf("Expected panic and stack to be logged, got %q", logs.String())
+		}
+	})
+
+	t.Run("returns internal error for connect requests", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithService("/api/", panicking),
+		)
+
+		resp := invoke(t, app, map[string]string{
+			"Content-Type":             "application/json",
+			"Connect-Protocol-Version": "1",
+		})
+
+		var connectErr struct {
+			Code string `json:"code"`
+		}
+
+		if err := json.Unmarshal([]byte(resp.Body), &connectErr); err != nil {
+			t.Fatalf("Expected a Connect error body, got %q: %v", resp.Body, err)
+		}
+
+		if connectErr.Code != "internal" {
+			t.Errorf("Expected code internal, got %q", connectErr.Code)
+		}
+	})
+
+	t.Run("reports to telemetry provider", func(t *testing.T) {
+		telemetry := &panicReportingTelemetry{}
+
+		app := dindenault.New(slog.Default(),
+			dindenault.WithTelemetry(telemetry, dindenault.TelemetryOptions{}),
+			dindenault.WithPlainService("/api/", panicking),
+		)
+
+		invoke(t, app, nil)
+
+		if telemetry.recovered != "boom" || len(telemetry.stack) == 0 {
+			t.Errorf("Expected panic to be reported, got %v", telemetry.recovered)
+		}
+	})
+
+	t.Run("custom panic handler", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithPanicHandler(func(w http.ResponseWriter, _ *http.Request, recovered any) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_, _ = w.Write([]byte("recovered " + recovered.(string)))
+			}),
+			dindenault.WithService("/api/", panicking),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusServiceUnavailable || resp.Body != "recovered boom" {
+			t.Errorf("Expected custom response, got %d %q", resp.StatusCode, resp.Body)
+		}
+	})
+
+	t.Run("keeps a started response", func(t *testing.T) {
+		app := dindenault.New(slog.Default(),
+			dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusAccepted)
+				panic("late")
+			})),
+		)
+
+		resp := invoke(t, app, nil)
+		if resp.StatusCode != http.StatusAccepted {
+			t.Errorf("Expected status 202, got %d", resp.StatusCode)
+		}
+	})
+}
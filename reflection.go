@@ -0,0 +1,76 @@
+package dindenault
+
+import (
+	"net/http"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
+)
+
+// WithReflection serves gRPC server reflection (grpc.reflection.v1 and
+// v1alpha) for the Connect services registered with the App, so that
+// grpcurl, buf curl and Postman can discover and call them without
+// access to the .proto files.
+//
+// The listed services are derived from the registration paths.
+// Descriptors are resolved from the protobuf global registry, where
+// generated code registers them; use grpcreflect.WithDescriptorResolver
+// to supply them explicitly, for example from a descriptor set.
+//
+// Reflection exposes the full schema of the services, including
+// messages that clients would otherwise not see. The reflection
+// handlers receive the app-level interceptors, so they are protected by
+// the same authentication as the services themselves.
+//
+// The reflection API is a bidirectional stream. Clients that keep the
+// stream open between requests, like grpcurl, need a full-duplex HTTP/2
+// connection: use them against ListenAndServe rather than a Lambda
+// function URL or load balancer, which buffer the request.
+//
+// Example:
+//
+//	app := dindenault.New(logger,
+//	    dindenault.WithService(servicev1connect.NewServiceHandler(impl)),
+//	    dindenault.WithReflection(),
+//	)
+func WithReflection(options ...grpcreflect.Option) Option {
+	return func(a *App) {
+		reflector := grpcreflect.NewReflector(grpcreflect.NamerFunc(a.ServiceNames), options...)
+
+		for _, newHandler := range []func(*grpcreflect.Reflector, ...connect.HandlerOption) (string, http.Handler){
+			grpcreflect.NewHandlerV1,
+			grpcreflect.NewHandlerV1Alpha,
+		} {
+			path, handler := newHandler(reflector)
+
+			a.registrations = append(a.registrations, Registration{
+				Path: path,
+				Handler: &reflectionHandler{
+					Handler:    handler,
+					reflector:  reflector,
+					newHandler: newHandler,
+				},
+			})
+		}
+	}
+}
+
+// reflectionHandler lets prepareHandlers apply app-level interceptors to
+// a reflection handler, which grpcreflect only accepts at construction.
+type reflectionHandler struct {
+	http.Handler
+
+	reflector  *grpcreflect.Reflector
+	newHandler func(*grpcreflect.Reflector, ...connect.HandlerOption) (string, http.Handler)
+}
+
+// WithInterceptors implements ConnectHandlerWithInterceptor.
+func (h *reflectionHandler) WithInterceptors(interceptors ...connect.Interceptor) http.Handler {
+	_, handler := h.newHandler(h.reflector, connect.WithInterceptors(interceptors...))
+
+	return &reflectionHandler{
+		Handler:    handler,
+		reflector:  h.reflector,
+		newHandler: h.newHandler,
+	}
+}
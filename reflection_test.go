@@ -0,0 +1,120 @@
+package dindenault_test
+
+import (
+	"context"
+	"log/slog"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpcreflect"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/navigacontentlab/dindenault"
+)
+
+// streamCountingInterceptor counts the streaming handler calls it sees.
+type streamCountingInterceptor struct {
+	calls int
+}
+
+func (i *streamCountingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return next
+}
+
+func (i *streamCountingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+func (i *streamCountingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		i.calls++
+
+		return next(ctx, conn)
+	}
+}
+
+// echoServiceFiles returns a registry with the descriptor of a
+// test.v1.EchoService, as generated code would register it.
+func echoServiceFiles(t *testing.T) *protoregistry.Files {
+	t.Helper()
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:        proto.String("test/v1/echo.proto"),
+		Package:     proto.String("test.v1"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Empty")}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("EchoService"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Echo"),
+				InputType:  proto.String(".test.v1.Empty"),
+				OutputType: proto.String(".test.v1.Empty"),
+			}},
+		}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to build descriptor: %v", err)
+	}
+
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(file); err != nil {
+		t.Fatalf("Failed to register descriptor: %v", err)
+	}
+
+	return files
+}
+
+func TestWithReflection(t *testing.T) {
+	interceptor := &streamCountingInterceptor{}
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithInterceptors(interceptor),
+		dindenault.WithService("/test.v1.EchoService/", &mockConnectHandler{}),
+		dindenault.WithHealth("/health"),
+		dindenault.WithReflection(grpcreflect.WithDescriptorResolver(echoServiceFiles(t))),
+	)
+
+	if names := app.ServiceNames(); !slices.Equal(names, []string{"test.v1.EchoService"}) {
+		t.Errorf("Expected only the echo service to be listed, got %v", names)
+	}
+
+	// Reflection is a bidirectional stream and needs HTTP/2.
+	server := httptest.NewUnstartedServer(app.HTTPHandler())
+	server.EnableHTTP2 = true
+	server.StartTLS()
+
+	defer server.Close()
+
+	client := grpcreflect.NewClient(server.Client(), server.URL, connect.WithGRPC())
+
+	stream := client.NewStream(context.Background())
+	defer func() { _, _ = stream.Close() }()
+
+	services, err := stream.ListServices()
+	if err != nil {
+		t.Fatalf("ListServices failed: %v", err)
+	}
+
+	if !slices.Equal(services, []protoreflect.FullName{"test.v1.EchoService"}) {
+		t.Errorf("Unexpected services %v", services)
+	}
+
+	files, err := stream.FileContainingSymbol("test.v1.EchoService")
+	if err != nil {
+		t.Fatalf("FileContainingSymbol failed: %v", err)
+	}
+
+	if len(files) != 1 || files[0].GetName() != "test/v1/echo.proto" {
+		t.Errorf("Unexpected files %v", files)
+	}
+
+	if interceptor.calls == 0 {
+		t.Error("Expected app-level interceptors to be applied to reflection")
+	}
+}
@@ -0,0 +1,56 @@
+package dindenault
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/navigacontentlab/dindenault/internal/requestid"
+)
+
+// RequestIDFromContext returns the ID of the current request, or an
+// empty string outside of a request.
+//
+// Every request served by the App gets an ID: the X-Request-Id header
+// sent by the client if it is well-formed, otherwise the API Gateway
+// request ID or the root of the X-Amzn-Trace-Id header added by ALB and
+// API Gateway, and otherwise a generated UUID. The ID is returned in
+// the X-Request-Id response header, added as "request_id" to records
+// logged through the App's logger with a request context, and forwarded
+// by navigaid.NewHTTPClient and mcp.NewHTTPClient.
+func RequestIDFromContext(ctx context.Context) string {
+	return requestid.FromContext(ctx)
+}
+
+// withRequestID assigns req its request ID, storing it in the context
+// and in the X-Request-Id header seen by handlers.
+func withRequestID(req *http.Request) *http.Request {
+	id := req.Header.Get(requestid.Header)
+
+	if !requestid.Valid(id) {
+		id = ""
+
+		if info, ok := LambdaRequestContext(req.Context()); ok {
+			id = info.RequestID
+		}
+	}
+
+	if !requestid.Valid(id) {
+		id = requestid.TraceRoot(req.Header.Get("X-Amzn-Trace-Id"))
+	}
+
+	if !requestid.Valid(id) {
+		id = requestid.New()
+	}
+
+	req = req.WithContext(requestid.WithID(req.Context(), id))
+	req.Header.Set(requestid.Header, id)
+
+	return req
+}
+
+// setRequestIDHeader echoes the request ID of req in the response.
+func setRequestIDHeader(w http.ResponseWriter, req *http.Request) {
+	if id := requestid.FromContext(req.Context()); id != "" {
+		w.Header().Set(requestid.Header, id)
+	}
+}
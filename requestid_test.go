@@ -0,0 +1,139 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestID(t *testing.T) {
+	var seen string
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/api/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			seen = dindenault.RequestIDFromContext(r.Context())
+
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "client request ID",
+			headers: map[string]string{"X-Request-Id": "client-id-1"},
+			want:    "client-id-1",
+		},
+		{
+			name:    "ALB trace ID",
+			headers: map[string]string{"X-Amzn-Trace-Id": "Self=1-abc;Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1"},
+			want:    "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			name:    "malformed client request ID is replaced",
+			headers: map[string]string{"X-Request-Id": "forged\nlog line"},
+		},
+		{
+			name: "generated",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Handle()(context.Background(), events.ALBTargetGroupRequest{
+				HTTPMethod: http.MethodGet,
+				Path:       "/api/items",
+				Headers:    tt.headers,
+			})
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got := resp.MultiValueHeaders["X-Request-Id"]
+			if len(got) != 1 || got[0] != seen {
+				t.Fatalf("Expected response header to match handler context %q, got %v", seen, got)
+			}
+
+			if tt.want != "" && seen != tt.want {
+				t.Errorf("Expected request ID %q, got %q", tt.want, seen)
+			}
+
+			if tt.want == "" && !uuidPattern.MatchString(seen) {
+				t.Errorf("Expected a generated UUID, got %q", seen)
+			}
+		})
+	}
+
+	t.Run("API Gateway request ID", func(t *testing.T) {
+		resp, err := app.HandleAPIGateway()(context.Background(), events.APIGatewayV2HTTPRequest{
+			Version: "2.0",
+			RawPath: "/api/items",
+			RequestContext: events.APIGatewayV2HTTPRequestContext{
+				RequestID: "gateway-id-1",
+				HTTP:      events.APIGatewayV2HTTPRequestContextHTTPDescription{Method: http.MethodGet},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if seen != "gateway-id-1" || resp.Headers["X-Request-Id"] != "gateway-id-1" {
+			t.Errorf("Expected gateway request ID, got %q / %q", seen, resp.Headers["X-Request-Id"])
+		}
+	})
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var forwarded string
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		forwarded = r.Header.Get("X-Request-Id")
+	}))
+	defer upstream.Close()
+
+	var logs bytes.Buffer
+
+	app := dindenault.New(slog.New(slog.NewTextHandler(&logs, nil)),
+		dindenault.WithService("/api/", http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+			resp, err := navigaid.NewHTTPClient(r.Context(), nil).Get(upstream.URL)
+			if err == nil {
+				_ = resp.Body.Close()
+			}
+
+			panic("after upstream call")
+		})),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items", nil)
+	req.Header.Set("X-Request-Id", "corr-42")
+
+	rec := httptest.NewRecorder()
+	app.HTTPHandler().ServeHTTP(rec, req)
+
+	if forwarded != "corr-42" {
+		t.Errorf("Expected request ID to be forwarded upstream, got %q", forwarded)
+	}
+
+	if !strings.Contains(logs.String(), "request_id=corr-42") {
+		t.Errorf("Expected App log records to carry the request ID, got %q", logs.String())
+	}
+
+	if rec.Header().Get("X-Request-Id") != "corr-42" {
+		t.Errorf("Expected request ID on the error response, got %q", rec.Header().Get("X-Request-Id"))
+	}
+}
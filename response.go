@@ -0,0 +1,88 @@
+package dindenault
+
+import (
+	"net/http"
+)
+
+// DefaultMaxResponseSize is the default limit for the encoded size of a
+// Lambda response. Lambda rejects synchronous responses larger than
+// 6 MB, which load balancers and gateways turn into opaque 502 errors.
+const DefaultMaxResponseSize = 6 * 1024 * 1024
+
+// DefaultBinaryMediaTypes returns the media types whose Lambda
+// responses are always base64 encoded by default: protobuf and gRPC
+// payloads, which may happen to be valid UTF-8 and would be corrupted
+// if sent as text.
+func DefaultBinaryMediaTypes() []string {
+	return []string{
+		"application/proto",
+		"application/x-protobuf",
+		"application/connect+proto",
+		"application/grpc*",
+		"application/octet-stream",
+	}
+}
+
+// WithBinaryMediaTypes sets the media types whose Lambda responses are
+// always base64 encoded, replacing DefaultBinaryMediaTypes. A media
+// type ending in "*" matches by prefix, e.g. "image/*".
+//
+// Regardless of this setting, responses with a Content-Encoding (such as
+// gzip-compressed Connect responses) are always base64 encoded, and any
+// other response is base64 encoded if its body is not valid UTF-8.
+//
+// Example - Also treat PDFs and images as binary:
+//
+//	app := dindenault.New(logger,
+//	    dindenault.WithBinaryMediaTypes(append(
+//	        dindenault.DefaultBinaryMediaTypes(),
+//	        "application/pdf", "image/*",
+//	    )...),
+//	    dindenault.WithService(path, handler),
+//	)
+func WithBinaryMediaTypes(mediaTypes ...string) Option {
+	return func(a *App) {
+		a.binaryMediaTypes = mediaTypes
+	}
+}
+
+// OversizedResponseHandler is called when a Lambda response would
+// exceed the configured size limit. It receives the original request,
+// and the headers and body of the response that was too large, and
+// writes a replacement response to w — for example a redirect to a
+// presigned URL the body has been uploaded to:
+//
+//	func(w http.ResponseWriter, r *http.Request, header http.Header, body []byte) {
+//	    url, err := uploadToS3(r.Context(), header.Get("Content-Type"), body)
+//	    if err != nil {
+//	        http.Error(w, "Response too large", http.StatusRequestEntityTooLarge)
+//	        return
+//	    }
+//	    http.Redirect(w, r, url, http.StatusSeeOther)
+//	}
+type OversizedResponseHandler func(w http.ResponseWriter, r *http.Request, header http.Header, body []byte)
+
+// WithMaxResponseSize sets the limit for the encoded size of Lambda
+// responses, in bytes. The size is measured on the final payload,
+// including the roughly 33% added by base64 encoding binary bodies.
+//
+// Responses above the limit are logged and replaced by a Connect
+// resource_exhausted error for RPC requests, or an HTTP 413 for other
+// requests — unless an OversizedResponseHandler is configured. The
+// default is DefaultMaxResponseSize; a limit of 0 disables the check.
+func WithMaxResponseSize(limit int) Option {
+	return func(a *App) {
+		a.maxResponseSize = limit
+	}
+}
+
+// WithOversizedResponseHandler sets a handler that is called instead
+// of returning an error when a Lambda response exceeds the size limit
+// (see WithMaxResponseSize), typically to offload the body elsewhere.
+// If the replacement response is too large as well, the error response
+// is returned.
+func WithOversizedResponseHandler(handler OversizedResponseHandler) Option {
+	return func(a *App) {
+		a.oversizedResponseHandler = handler
+	}
+}
@@ -0,0 +1,191 @@
+package dindenault
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/connect"
+	"connectrpc.com/grpchealth"
+
+	"github.com/navigacontentlab/dindenault/cors"
+)
+
+// WithNotFoundHandler sets the handler for requests that match no
+// registration. By default, Connect, gRPC and gRPC-Web requests receive
+// an unimplemented error in their own protocol, and other requests an
+// application/problem+json response with status 404.
+//
+// Like registered handlers, the handler runs behind the CORS middleware
+// configured with WithConnectRPC, so browser clients can read the
+// error.
+func WithNotFoundHandler(handler http.Handler) Option {
+	return func(a *App) {
+		a.notFoundHandler = handler
+	}
+}
+
+// WithMethodNotAllowedHandler sets the handler for requests whose path
+// matches a registration but whose method does not, for example a POST
+// to "GET /articles/{id}". The Allow header is set before the handler
+// is called. The default answers like the default not-found handler,
+// with status 405.
+func WithMethodNotAllowedHandler(handler http.Handler) Option {
+	return func(a *App) {
+		a.methodNotAllowedHandler = handler
+	}
+}
+
+// ServiceNames returns the fully qualified names of the Connect
+// services registered with the App, such as "pkg.v1.Service", derived
+// from registration paths of the form "/pkg.v1.Service/". The
+// grpc.health.v1 and grpc.reflection services are not included.
+func (a *App) ServiceNames() []string {
+	var names []string
+
+	for _, reg := range a.registrations {
+		name, ok := serviceName(reg.Path)
+		if !ok || name == grpchealth.HealthV1ServiceName || strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// serviceName returns the service name of a Connect service path.
+func serviceName(path string) (string, bool) {
+	if !strings.HasPrefix(path, "/") || !strings.HasSuffix(path, "/") {
+		return "", false
+	}
+
+	name := strings.Trim(path, "/")
+	if !strings.Contains(name, ".") || strings.ContainsAny(name, "/ {") {
+		return "", false
+	}
+
+	return name, true
+}
+
+// muxPatterns returns the http.ServeMux patterns that a registration
+// path is served under. Plain paths without a trailing slash also match
+// the subtree below them, like the prefix matching of earlier versions
+// but on segment boundaries. An empty path matches everything.
+func muxPatterns(path string) []string {
+	if path == "" {
+		return []string{"/"}
+	}
+
+	if strings.ContainsAny(path, " \t{") || strings.HasSuffix(path, "/") {
+		return []string{path}
+	}
+
+	return []string{path, path + "/"}
+}
+
+// newRouter builds the ServeMux for the prepared registrations. It
+// panics if a path is not a valid pattern or conflicts with another
+// registration.
+func newRouter(registrations []Registration) *http.ServeMux {
+	mux := http.NewServeMux()
+
+	for _, reg := range registrations {
+		for _, pattern := range muxPatterns(reg.Path) {
+			handle(mux, reg.Path, pattern, reg.Handler)
+		}
+	}
+
+	return mux
+}
+
+// handle registers handler with mux, turning ServeMux's panic for
+// invalid or conflicting patterns into one that names the registration.
+func handle(mux *http.ServeMux, path, pattern string, handler http.Handler) {
+	defer func() {
+		if r := recover(); r != nil {
+			panic(fmt.Sprintf("dindenault: cannot register handler at %q: %v", path, r))
+		}
+	}()
+
+	mux.Handle(pattern, handler)
+}
+
+// prepareFallbacks wraps the not-found and method-not-allowed handlers
+// with CORS and panic recovery, like registered handlers.
+func (a *App) prepareFallbacks() {
+	if a.notFoundHandler == nil {
+		a.notFoundHandler = http.HandlerFunc(defaultNotFoundHandler)
+	}
+
+	if a.methodNotAllowedHandler == nil {
+		a.methodNotAllowedHandler = http.HandlerFunc(defaultMethodNotAllowedHandler)
+	}
+
+	for _, handler := range []*http.Handler{&a.notFoundHandler, &a.methodNotAllowedHandler} {
+		if a.corsOptions != nil {
+			*handler = cors.Middleware(*a.corsOptions, *handler)
+		}
+
+		*handler = a.recoverPanics("", *handler)
+	}
+}
+
+// serveFallback answers a request that the router has no handler for.
+// fallback is the handler ServeMux would use; it is run against a
+// scratch writer only to tell a 405 (with its Allow header) from a 404.
+func (a *App) serveFallback(w http.ResponseWriter, req *http.Request, fallback http.Handler) {
+	probe := &fallbackProbe{header: make(http.Header)}
+	fallback.ServeHTTP(probe, req)
+
+	if probe.status != http.StatusMethodNotAllowed {
+		a.notFoundHandler.ServeHTTP(w, req)
+
+		return
+	}
+
+	w.Header().Set("Allow", probe.header.Get("Allow"))
+	a.methodNotAllowedHandler.ServeHTTP(w, req)
+}
+
+func defaultNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	if isRPCRequest(r) {
+		writeError(w, r, connect.CodeUnimplemented, http.StatusNotFound,
+			r.URL.Path+" is not implemented")
+
+		return
+	}
+
+	writeProblem(w, http.StatusNotFound, "No handler is registered for "+r.URL.Path)
+}
+
+func defaultMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	if isRPCRequest(r) {
+		writeError(w, r, connect.CodeUnimplemented, http.StatusMethodNotAllowed,
+			r.Method+" "+r.URL.Path+" is not implemented")
+
+		return
+	}
+
+	writeProblem(w, http.StatusMethodNotAllowed, r.Method+" is not allowed for "+r.URL.Path)
+}
+
+// fallbackProbe is a response writer that records the status code and
+// headers of ServeMux's built-in fallback handlers and discards the body.
+type fallbackProbe struct {
+	header http.Header
+	status int
+}
+
+func (p *fallbackProbe) Header() http.Header {
+	return p.header
+}
+
+func (p *fallbackProbe) Write(body []byte) (int, error) {
+	return len(body), nil
+}
+
+func (p *fallbackProbe) WriteHeader(status int) {
+	p.status = status
+}
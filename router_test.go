@@ -0,0 +1,188 @@
+package dindenault_test
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/cors"
+)
+
+func TestRouting(t *testing.T) {
+	named := func(name string) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(name + r.PathValue("id")))
+		})
+	}
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithService("/pkg.v1.Service/", named("service")),
+		dindenault.WithPlainService("/api", named("api")),
+		dindenault.WithPlainService("/api/admin/", named("admin")),
+		dindenault.WithPlainService("GET /articles/{id}", named("article:")),
+	)
+
+	handler := app.HTTPHandler()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "service prefix", method: http.MethodPost, path: "/pkg.v1.Service/Method", wantStatus: http.StatusOK, wantBody: "service"},
+		{name: "procedures are case-sensitive", method: http.MethodPost, path: "/PKG.v1.Service/Method", wantStatus: http.StatusNotFound},
+		{name: "plain path itself", method: http.MethodGet, path: "/api", wantStatus: http.StatusOK, wantBody: "api"},
+		{name: "plain path subtree", method: http.MethodGet, path: "/api/items", wantStatus: http.StatusOK, wantBody: "api"},
+		{name: "segment boundary", method: http.MethodGet, path: "/apiv2/items", wantStatus: http.StatusNotFound},
+		{name: "most specific wins", method: http.MethodGet, path: "/api/admin/users", wantStatus: http.StatusOK, wantBody: "admin"},
+		{name: "path parameter", method: http.MethodGet, path: "/articles/42", wantStatus: http.StatusOK, wantBody: "article:42"},
+		{name: "method mismatch", method: http.MethodDelete, path: "/articles/42", wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRoutingConflicts(t *testing.T) {
+	handler := http.NotFoundHandler()
+
+	app := dindenault.New(slog.Default(),
+		dindenault.WithPlainService("/webhook", handler),
+		dindenault.WithPlainService("/webhook/", handler),
+	)
+
+	defer func() {
+		recovered := recover()
+		if recovered == nil {
+			t.Fatal("Expected conflicting registrations to panic")
+		}
+
+		if msg, _ := recovered.(string); !strings.Contains(msg, "/webhook") {
+			t.Errorf("Expected panic to name the registration, got %v", recovered)
+		}
+	}()
+
+	app.HTTPHandler()
+}
+
+func TestNotFoundResponses(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithConnectRPC(cors.Options{AllowedDomains: []string{testWildcardDomain}}),
+		dindenault.WithPlainService("GET /articles/{id}", http.NotFoundHandler()),
+	)
+
+	handler := app.HTTPHandler()
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		return rec
+	}
+
+	t.Run("plain requests get problem details", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("Expected status 404, got %d", rec.Code)
+		}
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+			t.Errorf("Expected problem+json, got %q", ct)
+		}
+
+		var problem struct {
+			Title  string `json:"title"`
+			Status int    `json:"status"`
+		}
+
+		if err := json.Unmarshal(rec.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Invalid problem body %q: %v", rec.Body.String(), err)
+		}
+
+		if problem.Status != http.StatusNotFound || problem.Title != "Not Found" {
+			t.Errorf("Unexpected problem %+v", problem)
+		}
+	})
+
+	t.Run("connect requests get unimplemented", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/pkg.v1.Missing/Method", strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Connect-Protocol-Version", "1")
+		req.Header.Set("Origin", "https://app.example.com")
+
+		rec := serve(req)
+
+		var connectErr struct {
+			Code string `json:"code"`
+		}
+
+		if err := json.Unmarshal(rec.Body.Bytes(), &connectErr); err != nil {
+			t.Fatalf("Expected a Connect error body, got %q: %v", rec.Body.String(), err)
+		}
+
+		if connectErr.Code != "unimplemented" {
+			t.Errorf("Expected code unimplemented, got %q", connectErr.Code)
+		}
+
+		if rec.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Error("Expected CORS headers on the not-found response")
+		}
+	})
+
+	t.Run("method mismatch gets 405 with Allow", func(t *testing.T) {
+		rec := serve(httptest.NewRequest(http.MethodPost, "/articles/42", nil))
+
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("Expected status 405, got %d", rec.Code)
+		}
+
+		if allow := rec.Header().Get("Allow"); !strings.Contains(allow, http.MethodGet) {
+			t.Errorf("Expected Allow header to list GET, got %q", allow)
+		}
+	})
+
+	t.Run("custom handlers", func(t *testing.T) {
+		custom := dindenault.New(slog.Default(),
+			dindenault.WithPlainService("GET /articles/{id}", http.NotFoundHandler()),
+			dindenault.WithNotFoundHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})),
+			dindenault.WithMethodNotAllowedHandler(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+			})),
+		).HTTPHandler()
+
+		rec := httptest.NewRecorder()
+		custom.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+
+		if rec.Code != http.StatusTeapot {
+			t.Errorf("Expected custom not-found status, got %d", rec.Code)
+		}
+
+		rec = httptest.NewRecorder()
+		custom.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/articles/1", nil))
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("Expected custom method-not-allowed status, got %d", rec.Code)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package dindenault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultShutdownTimeout bounds how long ListenAndServe waits for
+	// in-flight requests to finish during graceful shutdown.
+	defaultShutdownTimeout = 10 * time.Second
+
+	// defaultReadHeaderTimeout protects the local server against
+	// clients that never finish sending headers.
+	defaultReadHeaderTimeout = 10 * time.Second
+)
+
+// HTTPHandler returns an http.Handler that serves the App's
+// registrations directly, without going through a Lambda event.
+//
+// The handler uses the same prepared registrations as Handle and
+// HandleAPIGateway — app-level interceptors, telemetry, CORS and path
+// routing all behave exactly as they do in Lambda. This makes it
+// possible to run the production App locally (for example in
+// docker-compose) and talk to it with ordinary Connect clients or curl:
+//
+//	app := dindenault.New(logger, dindenault.WithService(path, handler))
+//
+//	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") == "" {
+//	    log.Fatal(http.ListenAndServe(":8080", app.HTTPHandler()))
+//	}
+//
+//	lambda.Start(app.Handle())
+func (a *App) HTTPHandler() http.Handler {
+	a.prepareHandlers()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.dispatch(w, withRequestID(r))
+	})
+}
+
+// ListenAndServe serves the App over HTTP on addr until the process
+// receives SIGINT or SIGTERM, then shuts the server down gracefully.
+//
+// It is a convenience for local development; see HTTPHandler for how
+// requests are routed. Use ListenAndServeContext to control shutdown
+// with a context instead of signals.
+func (a *App) ListenAndServe(addr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return a.ListenAndServeContext(ctx, addr)
+}
+
+// ListenAndServeContext serves the App over HTTP on addr until ctx is
+// cancelled, then shuts the server down gracefully, waiting for
+// in-flight requests to complete.
+//
+// It returns nil after a clean shutdown, and an error if the server
+// could not be started or did not shut down in time.
+func (a *App) ListenAndServeContext(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	return a.serve(ctx, listener)
+}
+
+// serve runs an HTTP server on listener until ctx is cancelled.
+func (a *App) serve(ctx context.Context, listener net.Listener) error {
+	// Accept HTTP/2 without TLS (h2c) as well, so that gRPC clients and
+	// full-duplex streams such as server reflection work locally.
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	protocols.SetUnencryptedHTTP2(true)
+
+	server := &http.Server{
+		Handler:           a.HTTPHandler(),
+		Protocols:         protocols,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		BaseContext: func(net.Listener) context.Context {
+			// Detach from ctx so that cancelling it starts a graceful
+			// shutdown instead of aborting in-flight requests.
+			return context.WithoutCancel(ctx)
+		},
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		a.logger.Info("Serving HTTP", "addr", listener.Addr().String())
+
+		errCh <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("http server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	a.logger.Info("Shutting down HTTP server")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), defaultShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("failed to shut down http server: %w", err)
+	}
+
+	if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("http server failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package dindenault_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/cors"
+)
+
+func TestHTTPHandler(t *testing.T) {
+	logger := slog.Default()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("served " + r.URL.Path))
+	})
+
+	app := dindenault.New(logger,
+		dindenault.WithConnectRPC(cors.Options{AllowedDomains: []string{testWildcardDomain}}),
+		dindenault.WithService("/service.v1.Service/", handler),
+	)
+
+	server := httptest.NewServer(app.HTTPHandler())
+	defer server.Close()
+
+	t.Run("routes to registered handler", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodPost, server.URL+"/service.v1.Service/Method", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+
+		req.Header.Set("Origin", "https://app.example.com")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		defer func() { _ = resp.Body.Close() }()
+
+		body, _ := io.ReadAll(resp.Body)
+		if string(body) != "served /service.v1.Service/Method" {
+			t.Errorf("Unexpected body %q", body)
+		}
+
+		if resp.Header.Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+			t.Error("Expected CORS headers to be applied")
+		}
+	})
+
+	t.Run("returns 404 for unknown paths", func(t *testing.T) {
+		resp, err := http.Get(server.URL + "/unknown")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected status 404, got %d", resp.StatusCode)
+		}
+	})
+}
+
+func TestListenAndServeContext(t *testing.T) {
+	logger := slog.Default()
+
+	// Reserve a free port for the server.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to reserve port: %v", err)
+	}
+
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	app := dindenault.New(logger,
+		dindenault.WithService("/health", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- app.ListenAndServeContext(ctx, addr)
+	}()
+
+	var resp *http.Response
+
+	for range 50 {
+		resp, err = http.Get("http://" + addr + "/health")
+		if err == nil {
+			break
+		}
+
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if err != nil {
+		t.Fatalf("Server did not start: %v", err)
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+}
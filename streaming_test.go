@@ -0,0 +1,170 @@
+package dindenault_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+const watchProcedure = "/test.v1.StreamService/Watch"
+
+func TestStreamingInterceptors(t *testing.T) {
+	var logs bytes.Buffer
+
+	logger := slog.New(slog.NewJSONHandler(&logs, nil))
+
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(token string) (navigaid.Claims, error) {
+		if token == "invalid" {
+			return navigaid.Claims{}, errors.New("invalid token")
+		}
+
+		return navigaid.Claims{
+			Org:         "acme",
+			Permissions: navigaid.PermissionsClaim{Org: strings.Split(token, ",")},
+		}, nil
+	})
+
+	var calls int
+
+	handler := connect.NewServerStreamHandler(watchProcedure,
+		func(_ context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[emptypb.Empty]) error {
+			calls++
+
+			for range 2 {
+				if err := stream.Send(&emptypb.Empty{}); err != nil {
+					return err //nolint:wrapcheck // test handler
+				}
+			}
+
+			return nil
+		},
+		connect.WithInterceptors(
+			dindenault.LoggingInterceptors(logger),
+			navigaid.ConnectInterceptor(logger, jwks),
+			navigaid.RequirePermission(logger, "stream:read"),
+			dindenault.PathInterceptors(logger, []dindenault.PathPermissionConfig{
+				{PathPrefix: watchProcedure, Permissions: []string{"stream:watch"}},
+			}),
+		),
+	)
+
+	app := dindenault.New(logger, dindenault.WithPlainService("/test.v1.StreamService/", handler))
+
+	server := httptest.NewServer(app.HTTPHandler())
+	defer server.Close()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+watchProcedure)
+
+	watch := func(t *testing.T, token string) (int, error) {
+		t.Helper()
+
+		req := connect.NewRequest(&emptypb.Empty{})
+		if token != "" {
+			req.Header().Set("Authorization", "Bearer "+token)
+		}
+
+		stream, err := client.CallServerStream(context.Background(), req)
+		if err != nil {
+			return 0, err //nolint:wrapcheck // test helper
+		}
+
+		defer func() { _ = stream.Close() }()
+
+		var received int
+		for stream.Receive() {
+			received++
+		}
+
+		return received, stream.Err() //nolint:wrapcheck // test helper
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		code  connect.Code
+	}{
+		{name: "no token", code: connect.CodeUnauthenticated},
+		{name: "invalid token", token: "invalid", code: connect.CodeUnauthenticated},
+		{name: "missing permission", token: "stream:watch", code: connect.CodePermissionDenied},
+		{name: "missing path permission", token: "stream:read", code: connect.CodePermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = 0
+
+			_, err := watch(t, tt.token)
+			if connect.CodeOf(err) != tt.code {
+				t.Errorf("Expected %v, got %v", tt.code, err)
+			}
+
+			if calls != 0 {
+				t.Error("Handler must not run when a check fails")
+			}
+		})
+	}
+
+	t.Run("authorized", func(t *testing.T) {
+		logs.Reset()
+
+		received, err := watch(t, "stream:read,stream:watch")
+		if err != nil || received != 2 {
+			t.Fatalf("Expected 2 messages, got %d: %v", received, err)
+		}
+
+		for _, message := range []string{"Connect RPC request started", "Connect RPC request completed"} {
+			if !strings.Contains(logs.String(), message) {
+				t.Errorf("Expected %q to be logged, got %s", message, logs.String())
+			}
+		}
+
+		if !strings.Contains(logs.String(), `"stream_type":"server"`) {
+			t.Errorf("Expected the stream type to be logged, got %s", logs.String())
+		}
+	})
+}
+
+func TestLoggingInterceptorsStreamingClient(t *testing.T) {
+	var logs bytes.Buffer
+
+	handler := connect.NewServerStreamHandler(watchProcedure,
+		func(context.Context, *connect.Request[emptypb.Empty], *connect.ServerStream[emptypb.Empty]) error {
+			return connect.NewError(connect.CodeUnavailable, errors.New("try again later"))
+		},
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle(watchProcedure, handler)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := connect.NewClient[emptypb.Empty, emptypb.Empty](server.Client(), server.URL+watchProcedure,
+		connect.WithInterceptors(dindenault.LoggingInterceptors(slog.New(slog.NewJSONHandler(&logs, nil)))))
+
+	stream, err := client.CallServerStream(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+	if err != nil {
+		t.Fatalf("Failed to start stream: %v", err)
+	}
+
+	for stream.Receive() {
+	}
+
+	_ = stream.Close()
+
+	if !strings.Contains(logs.String(), "Connect RPC request failed") || !strings.Contains(logs.String(), "try again later") {
+		t.Errorf("Expected the failed stream to be logged, got %s", logs.String())
+	}
+}
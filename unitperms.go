@@ -0,0 +1,279 @@
+package dindenault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// ErrorDomain is the domain of the google.rpc.ErrorInfo details
+	// that dindenault adds to errors.
+	ErrorDomain = "dindenault"
+
+	// ReasonMissingUnitPermission is the ErrorInfo reason of
+	// permission_denied errors for unit-scoped permission checks. The
+	// metadata holds the "unit" and the required "permissions",
+	// separated by commas.
+	ReasonMissingUnitPermission = "MISSING_UNIT_PERMISSION"
+)
+
+// UnitExtractor returns the unit that a request applies to. For Connect
+// procedures msg is the request message; for plain HTTP services it is
+// the *http.Request.
+type UnitExtractor func(ctx context.Context, msg any) (string, error)
+
+// UnitField returns a UnitExtractor that reads a string field of the
+// request message. The field is given by its protobuf name, or a path
+// of names separated by dots ("unit", "article.unit") in which all but
+// the last field are singular message fields.
+//
+// Example:
+//
+//	dindenault.PathPermissionConfig{
+//	    PathPrefix:  "/article.v1.ArticleService/Publish",
+//	    Permissions: []string{"articles:publish"},
+//	    Unit:        dindenault.UnitField("unit"),
+//	}
+func UnitField(path string) UnitExtractor {
+	return func(_ context.Context, msg any) (string, error) {
+		m, ok := msg.(proto.Message)
+		if !ok {
+			return "", fmt.Errorf("request %T is not a protobuf message", msg)
+		}
+
+		message := m.ProtoReflect()
+
+		fields, err := resolveUnitField(message.Descriptor(), path)
+		if err != nil {
+			return "", err
+		}
+
+		for _, field := range fields[:len(fields)-1] {
+			message = message.Get(field).Message()
+		}
+
+		return message.Get(fields[len(fields)-1]).String(), nil
+	}
+}
+
+// resolveUnitField returns the fields along a unit field path, checking
+// that they lead to a singular string field.
+func resolveUnitField(message protoreflect.MessageDescriptor, path string) ([]protoreflect.FieldDescriptor, error) {
+	names := strings.Split(path, ".")
+	fields := make([]protoreflect.FieldDescriptor, 0, len(names))
+
+	for i, name := range names {
+		field := message.Fields().ByName(protoreflect.Name(name))
+
+		last := i == len(names)-1
+
+		switch {
+		case field == nil:
+			return nil, fmt.Errorf("unit field %q does not exist in %s", path, message.FullName())
+		case field.Cardinality() == protoreflect.Repeated:
+			return nil, fmt.Errorf("unit field %q of %s is repeated", path, message.FullName())
+		case last && field.Kind() != protoreflect.StringKind:
+			return nil, fmt.Errorf("unit field %q of %s is not a string", path, message.FullName())
+		case !last && field.Message() == nil:
+			return nil, fmt.Errorf("unit field %q of %s: %s is not a message", path, message.FullName(), name)
+		}
+
+		fields = append(fields, field)
+
+		if !last {
+			message = field.Message()
+		}
+	}
+
+	return fields, nil
+}
+
+// unitPermissionDenied returns the permission_denied error for a caller
+// that lacks permissions in a unit, with a google.rpc.ErrorInfo detail
+// naming the unit and the permissions.
+func unitPermissionDenied(unit string, permissions []string) *connect.Error {
+	err := connect.NewError(connect.CodePermissionDenied,
+		fmt.Errorf("missing required permission in unit %s: %s", unit, strings.Join(permissions, ", ")))
+
+	detail, detailErr := connect.NewErrorDetail(&errdetails.ErrorInfo{
+		Reason: ReasonMissingUnitPermission,
+		Domain: ErrorDomain,
+		Metadata: map[string]string{
+			"unit":        unit,
+			"permissions": strings.Join(permissions, ","),
+		},
+	})
+	if detailErr == nil {
+		err.AddDetail(detail)
+	}
+
+	return err
+}
+
+// errNoUnit is returned when a unit-scoped check finds no unit in the
+// request.
+var errNoUnit = errors.New("the request does not name a unit")
+
+// extractUnit runs a UnitExtractor, mapping failures to Connect errors:
+// permission_denied if the unit cannot be read (a configuration error,
+// so the check fails closed; the cause is logged) and invalid_argument
+// if it is empty.
+func extractUnit(ctx context.Context, logger *slog.Logger, extractor UnitExtractor, msg any) (string, *connect.Error) {
+	unit, err := extractor(ctx, msg)
+	if err != nil {
+		logger.ErrorContext(ctx, "cannot read unit from request", "error", err)
+
+		return "", connect.NewError(connect.CodePermissionDenied, errors.New("cannot determine unit"))
+	}
+
+	if unit == "" {
+		return "", connect.NewError(connect.CodeInvalidArgument, errNoUnit)
+	}
+
+	return unit, nil
+}
+
+// errNoCheckedUnit is returned when a streaming handler sends, or
+// returns, before a received message has passed the unit check.
+var errNoCheckedUnit = errors.New("no request message naming a permitted unit was received")
+
+// serveUnitChecked runs a streaming handler whose unit permissions are
+// checked on every message it receives. It fails closed: once a
+// received message fails check the handler's outcome is replaced by the
+// check error, and unless a received message has passed check it is
+// replaced by permission denied, so a handler that never receives is
+// not served either.
+func serveUnitChecked(
+	ctx context.Context, next connect.StreamingHandlerFunc, conn connect.StreamingHandlerConn, check func(msg any) error,
+) error {
+	checking := &unitCheckingConn{StreamingHandlerConn: conn, check: check}
+
+	err := next(ctx, checking)
+
+	checking.mu.Lock()
+	defer checking.mu.Unlock()
+
+	switch {
+	case checking.checkErr != nil:
+		return checking.checkErr
+	case checking.authorized:
+		return err
+	default:
+		return connect.NewError(connect.CodePermissionDenied, errNoCheckedUnit)
+	}
+}
+
+// unitCheckingConn checks the unit permissions of every message that a
+// streaming handler receives. Until a received message has passed the
+// check nothing goes out: Send fails with permission denied, and
+// response headers and trailers are held back and only copied to the
+// stream once the check passes. A failed check is sticky: every later
+// Receive and Send returns the check error.
+type unitCheckingConn struct {
+	connect.StreamingHandlerConn
+
+	check func(msg any) error
+
+	mu         sync.Mutex
+	authorized bool
+	checkErr   error
+	header     http.Header
+	trailer    http.Header
+}
+
+func (c *unitCheckingConn) Receive(msg any) error {
+	c.mu.Lock()
+	checkErr := c.checkErr
+	c.mu.Unlock()
+
+	if checkErr != nil {
+		return checkErr
+	}
+
+	if err := c.StreamingHandlerConn.Receive(msg); err != nil {
+		return err //nolint:wrapcheck // io.EOF must be returned as is
+	}
+
+	err := c.check(msg)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err != nil {
+		c.checkErr = err
+
+		return err
+	}
+
+	if !c.authorized {
+		c.authorized = true
+
+		copyHeader(c.StreamingHandlerConn.ResponseHeader(), c.header)
+		copyHeader(c.StreamingHandlerConn.ResponseTrailer(), c.trailer)
+	}
+
+	return nil
+}
+
+func (c *unitCheckingConn) Send(msg any) error {
+	c.mu.Lock()
+	authorized, checkErr := c.authorized, c.checkErr
+	c.mu.Unlock()
+
+	if checkErr != nil {
+		return checkErr
+	}
+
+	if !authorized {
+		return connect.NewError(connect.CodePermissionDenied, errNoCheckedUnit)
+	}
+
+	return c.StreamingHandlerConn.Send(msg) //nolint:wrapcheck // transparent wrapper
+}
+
+func (c *unitCheckingConn) ResponseHeader() http.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.authorized {
+		return c.StreamingHandlerConn.ResponseHeader()
+	}
+
+	if c.header == nil {
+		c.header = make(http.Header)
+	}
+
+	return c.header
+}
+
+func (c *unitCheckingConn) ResponseTrailer() http.Header {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.authorized {
+		return c.StreamingHandlerConn.ResponseTrailer()
+	}
+
+	if c.trailer == nil {
+		c.trailer = make(http.Header)
+	}
+
+	return c.trailer
+}
+
+// copyHeader adds the values of src to dst.
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = append(dst[key], values...)
+	}
+}
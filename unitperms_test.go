@@ -0,0 +1,409 @@
+package dindenault_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/navigacontentlab/dindenault"
+	"github.com/navigacontentlab/dindenault/navigaid"
+)
+
+// unitJWKS accepts any token, granting the "unit-reader" token the
+// articles:read permission in unit-a.
+func unitJWKS() *navigaid.JWKS {
+	jwks := navigaid.NewJWKS("http://jwks.invalid")
+	jwks.SetValidationFunc(func(token string) (navigaid.Claims, error) {
+		claims := navigaid.Claims{Org: "acme"}
+		if token == "unit-reader" {
+			claims.Permissions.Units = map[string][]string{"unit-a": {"articles:read"}}
+		}
+
+		return claims, nil
+	})
+
+	return jwks
+}
+
+func TestUnitField(t *testing.T) {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("article.proto"),
+		Options: &descriptorpb.FileOptions{JavaPackage: proto.String("unit-a")},
+	}
+
+	tests := []struct {
+		path    string
+		msg     any
+		unit    string
+		wantErr bool
+	}{
+		{path: "name", msg: file, unit: "article.proto"},
+		{path: "options.java_package", msg: file, unit: "unit-a"},
+		{path: "options.go_package", msg: file, unit: ""},
+		{path: "syntax", msg: &descriptorpb.FileDescriptorProto{}, unit: ""},
+		{path: "missing", msg: file, wantErr: true},
+		{path: "dependency", msg: file, wantErr: true},
+		{path: "options", msg: file, wantErr: true},
+		{path: "name.value", msg: file, wantErr: true},
+		{path: "name", msg: "not a message", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			unit, err := dindenault.UnitField(tt.path)(context.Background(), tt.msg)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got unit %q", unit)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if unit != tt.unit {
+				t.Errorf("Expected unit %q, got %q", tt.unit, unit)
+			}
+		})
+	}
+}
+
+func TestPathPolicyInterceptorsUnit(t *testing.T) {
+	policy := dindenault.PathPermissionPolicy{
+		Configs: []dindenault.PathPermissionConfig{
+			{
+				PathPrefix:  "/test.v1.ArticleService/",
+				Permissions: []string{"articles:read"},
+				Unit:        dindenault.UnitField("value"),
+			},
+		},
+	}
+
+	interceptors := connect.WithInterceptors(
+		dindenault.PathPolicyInterceptors(slog.Default(), policy),
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/test.v1.ArticleService/Get", connect.NewUnaryHandler("/test.v1.ArticleService/Get",
+		func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[emptypb.Empty], error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}, interceptors))
+	mux.Handle("/test.v1.ArticleService/Publish", connect.NewClientStreamHandler("/test.v1.ArticleService/Publish",
+		func(_ context.Context, stream *connect.ClientStream[wrapperspb.StringValue]) (*connect.Response[emptypb.Empty], error) {
+			for stream.Receive() {
+			}
+
+			if err := stream.Err(); err != nil {
+				return nil, err //nolint:wrapcheck // returned to the client as is
+			}
+
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		}, interceptors))
+
+	server := httptest.NewServer(navigaid.HTTPMiddleware(slog.Default(), unitJWKS(), mux))
+	defer server.Close()
+
+	get := func(unit string) error {
+		client := connect.NewClient[wrapperspb.StringValue, emptypb.Empty](server.Client(),
+			server.URL+"/test.v1.ArticleService/Get")
+
+		req := connect.NewRequest(wrapperspb.String(unit))
+		req.Header().Set("Authorization", "Bearer unit-reader")
+
+		_, err := client.CallUnary(context.Background(), req)
+
+		return err //nolint:wrapcheck // test helper
+	}
+
+	publish := func(units ...string) error {
+		client := connect.NewClient[wrapperspb.StringValue, emptypb.Empty](server.Client(),
+			server.URL+"/test.v1.ArticleService/Publish")
+
+		stream := client.CallClientStream(context.Background())
+		stream.RequestHeader().Set("Authorization", "Bearer unit-reader")
+
+		for _, unit := range units {
+			if err := stream.Send(wrapperspb.String(unit)); err != nil {
+				break
+			}
+		}
+
+		_, err := stream.CloseAndReceive()
+
+		return err //nolint:wrapcheck // test helper
+	}
+
+	if err := get("unit-a"); err != nil {
+		t.Errorf("Expected permitted unit to be allowed, got %v", err)
+	}
+
+	if err := get(""); connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Errorf("Expected request without unit to be invalid, got %v", err)
+	}
+
+	err := get("unit-b")
+	if connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Fatalf("Expected other unit to be denied, got %v", err)
+	}
+
+	var connectErr *connect.Error
+	if !errors.As(err, &connectErr) {
+		t.Fatalf("Expected a connect error, got %T", err)
+	}
+
+	var info *errdetails.ErrorInfo
+
+	for _, detail := range connectErr.Details() {
+		value, valueErr := detail.Value()
+		if valueErr != nil {
+			t.Fatalf("Failed to decode error detail: %v", valueErr)
+		}
+
+		if v, ok := value.(*errdetails.ErrorInfo); ok {
+			info = v
+		}
+	}
+
+	if info == nil {
+		t.Fatal("Expected an ErrorInfo detail")
+	}
+
+	if info.GetReason() != dindenault.ReasonMissingUnitPermission || info.GetDomain() != dindenault.ErrorDomain {
+		t.Errorf("Unexpected reason %q in domain %q", info.GetReason(), info.GetDomain())
+	}
+
+	if info.GetMetadata()["unit"] != "unit-b" || info.GetMetadata()["permissions"] != "articles:read" {
+		t.Errorf("Unexpected metadata %v", info.GetMetadata())
+	}
+
+	if err := publish("unit-a", "unit-a"); err != nil {
+		t.Errorf("Expected stream in permitted unit to be allowed, got %v", err)
+	}
+
+	if err := publish("unit-a", "unit-b"); connect.CodeOf(err) != connect.CodePermissionDenied {
+		t.Errorf("Expected stream with other unit to be denied, got %v", err)
+	}
+}
+
+func TestPathPolicyInterceptorsUnitBidi(t *testing.T) {
+	policy := dindenault.PathPermissionPolicy{
+		Configs: []dindenault.PathPermissionConfig{
+			{
+				PathPrefix:  "/test.v1.ArticleService/",
+				Permissions: []string{"articles:read"},
+				Unit:        dindenault.UnitField("value"),
+			},
+		},
+	}
+
+	interceptors := connect.WithInterceptors(
+		dindenault.PathPolicyInterceptors(slog.Default(), policy),
+	)
+
+	sendErrs := make(chan error, 1)
+
+	handlers := map[string]func(*connect.BidiStream[wrapperspb.StringValue, wrapperspb.StringValue]) error{
+		// SendFirst sends before it receives anything.
+		"SendFirst": func(stream *connect.BidiStream[wrapperspb.StringValue, wrapperspb.StringValue]) error {
+			stream.ResponseHeader().Set("X-Leak", "secret")
+
+			sendErrs <- stream.Send(wrapperspb.String("secret"))
+
+			return nil
+		},
+		// Echo receives a message before it answers.
+		"Echo": func(stream *connect.BidiStream[wrapperspb.StringValue, wrapperspb.StringValue]) error {
+			stream.ResponseHeader().Set("X-Echo", "yes")
+
+			msg, err := stream.Receive()
+			if err != nil {
+				return err //nolint:wrapcheck // returned to the client as is
+			}
+
+			return stream.Send(msg) //nolint:wrapcheck // returned to the client as is
+		},
+		// IgnoreCheck echoes messages and answers even when a received
+		// message fails the unit check.
+		"IgnoreCheck": func(stream *connect.BidiStream[wrapperspb.StringValue, wrapperspb.StringValue]) error {
+			for {
+				msg, err := stream.Receive()
+				if errors.Is(err, io.EOF) {
+					return nil
+				}
+
+				if err != nil {
+					sendErrs <- stream.Send(wrapperspb.String("secret"))
+
+					return nil
+				}
+
+				if err := stream.Send(msg); err != nil {
+					return err //nolint:wrapcheck // returned to the client as is
+				}
+			}
+		},
+	}
+
+	mux := http.NewServeMux()
+
+	for name, handler := range handlers {
+		procedure := "/test.v1.ArticleService/" + name
+
+		mux.Handle(procedure, connect.NewBidiStreamHandler(procedure,
+			func(_ context.Context, stream *connect.BidiStream[wrapperspb.StringValue, wrapperspb.StringValue]) error {
+				return handler(stream)
+			}, interceptors))
+	}
+
+	// Bidi streams need HTTP/2.
+	server := httptest.NewUnstartedServer(navigaid.HTTPMiddleware(slog.Default(), unitJWKS(), mux))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+
+	defer server.Close()
+
+	openStream := func(t *testing.T, name string, units ...string) *connect.BidiStreamForClient[wrapperspb.StringValue, wrapperspb.StringValue] {
+		t.Helper()
+
+		client := connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](server.Client(),
+			server.URL+"/test.v1.ArticleService/"+name)
+
+		stream := client.CallBidiStream(context.Background())
+		stream.RequestHeader().Set("Authorization", "Bearer unit-reader")
+
+		for _, unit := range units {
+			if err := stream.Send(wrapperspb.String(unit)); err != nil {
+				t.Fatalf("Failed to send: %v", err)
+			}
+		}
+
+		if err := stream.CloseRequest(); err != nil {
+			t.Fatalf("Failed to close request: %v", err)
+		}
+
+		t.Cleanup(func() { _ = stream.CloseResponse() })
+
+		return stream
+	}
+
+	call := func(t *testing.T, name, unit string) (*wrapperspb.StringValue, http.Header, error) {
+		t.Helper()
+
+		stream := openStream(t, name, unit)
+		msg, err := stream.Receive()
+
+		return msg, stream.ResponseHeader(), err
+	}
+
+	t.Run("send before receive", func(t *testing.T) {
+		msg, header, err := call(t, "SendFirst", "unit-a")
+		if connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Errorf("Expected permission denied, got %v %v", msg, err)
+		}
+
+		if sendErr := <-sendErrs; connect.CodeOf(sendErr) != connect.CodePermissionDenied {
+			t.Errorf("Expected the handler's send to fail, got %v", sendErr)
+		}
+
+		if header.Get("X-Leak") != "" {
+			t.Error("Response headers were sent before the unit was checked")
+		}
+	})
+
+	t.Run("receive before send", func(t *testing.T) {
+		msg, header, err := call(t, "Echo", "unit-a")
+		if err != nil || msg.GetValue() != "unit-a" {
+			t.Fatalf("Expected the message to be echoed, got %v %v", msg, err)
+		}
+
+		if header.Get("X-Echo") != "yes" {
+			t.Errorf("Expected held back headers to be sent, got %v", header)
+		}
+	})
+
+	t.Run("other unit", func(t *testing.T) {
+		if _, _, err := call(t, "Echo", "unit-b"); connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Errorf("Expected permission denied, got %v", err)
+		}
+	})
+
+	t.Run("other unit after a permitted one", func(t *testing.T) {
+		stream := openStream(t, "IgnoreCheck", "unit-a", "unit-b")
+
+		if msg, err := stream.Receive(); err != nil || msg.GetValue() != "unit-a" {
+			t.Fatalf("Expected the permitted message to be echoed, got %v %v", msg, err)
+		}
+
+		if msg, err := stream.Receive(); connect.CodeOf(err) != connect.CodePermissionDenied {
+			t.Errorf("Expected permission denied, got %v %v", msg, err)
+		}
+
+		if sendErr := <-sendErrs; connect.CodeOf(sendErr) != connect.CodePermissionDenied {
+			t.Errorf("Expected the handler's send to fail, got %v", sendErr)
+		}
+	})
+}
+
+func TestWithPathPermissionPolicyServiceUnit(t *testing.T) {
+	app := dindenault.New(slog.Default(),
+		dindenault.WithPathPermissionPolicyService("/api/", unitJWKS(),
+			http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}),
+			dindenault.PathPermissionPolicy{
+				Configs: []dindenault.PathPermissionConfig{
+					{
+						PathPrefix:  "/api/articles",
+						Permissions: []string{"articles:read"},
+						Unit: func(_ context.Context, msg any) (string, error) {
+							req, ok := msg.(*http.Request)
+							if !ok {
+								return "", errors.New("not an HTTP request")
+							}
+
+							return req.URL.Query().Get("unit"), nil
+						},
+					},
+				},
+			},
+		),
+	)
+
+	handler := app.HTTPHandler()
+
+	tests := []struct {
+		unit   string
+		status int
+	}{
+		{unit: "unit-a", status: http.StatusNoContent},
+		{unit: "unit-b", status: http.StatusForbidden},
+		{status: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.unit, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/articles?unit="+tt.unit, nil)
+			req.Header.Set("Authorization", "Bearer unit-reader")
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, rec.Code)
+			}
+		})
+	}
+}
@@ -7,7 +7,11 @@ package xray
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
+	"sync"
 
 	"connectrpc.com/connect"
 	awsxray "github.com/aws/aws-xray-sdk-go/xray"
@@ -32,33 +36,119 @@ func (p *Provider) Initialize(_ context.Context, _ string, _ dindenault.Telemetr
 
 // Interceptor implements dindenault.TelemetryProvider.
 // Creates an X-Ray subsegment per RPC call and annotates it with the
-// procedure name and (optionally) the caller's organization.
+// procedure name and (optionally) the caller's organization. Streams
+// get a subsegment that lasts until the handler returns or the client
+// closes the response.
 //
 //nolint:ireturn // Returning interface as intended by TelemetryProvider design
 func (p *Provider) Interceptor(logger *slog.Logger, opts dindenault.TelemetryOptions) connect.Interceptor {
-	return connect.UnaryInterceptorFunc(func(next connect.UnaryFunc) connect.UnaryFunc {
-		return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-			procedure := req.Spec().Procedure
+	return &interceptor{logger: logger, opts: opts}
+}
 
-			ctx, seg := awsxray.BeginSubsegment(ctx, procedure)
+type interceptor struct {
+	logger *slog.Logger
+	opts   dindenault.TelemetryOptions
+}
 
-			if err := awsxray.AddAnnotation(ctx, "procedure", procedure); err != nil {
-				logger.Debug("X-Ray annotation failed", "error", err)
-			}
+func (i *interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		ctx, seg := i.begin(ctx, req.Spec().Procedure)
 
-			if opts.OrganizationFn != nil {
-				if org := opts.OrganizationFn(ctx); org != "" {
-					if err := awsxray.AddAnnotation(ctx, "organization", org); err != nil {
-						logger.Debug("X-Ray annotation failed", "error", err)
-					}
-				}
-			}
+		resp, err := next(ctx, req)
+		seg.Close(err)
+
+		return resp, err
+	}
+}
+
+func (i *interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		ctx, seg := i.begin(ctx, spec.Procedure)
 
-			resp, err := next(ctx, req)
-			seg.Close(err)
-			return resp, err
+		return &streamingClientConn{
+			StreamingClientConn: next(ctx, spec),
+			finish:              seg.Close,
 		}
+	}
+}
+
+func (i *interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, seg := i.begin(ctx, conn.Spec().Procedure)
+
+		err := next(ctx, conn)
+		seg.Close(err)
+
+		return err
+	}
+}
+
+// begin starts an annotated subsegment for procedure.
+func (i *interceptor) begin(ctx context.Context, procedure string) (context.Context, *awsxray.Segment) {
+	ctx, seg := awsxray.BeginSubsegment(ctx, procedure)
+
+	if err := awsxray.AddAnnotation(ctx, "procedure", procedure); err != nil {
+		i.logger.Debug("X-Ray annotation failed", "error", err)
+	}
+
+	if i.opts.OrganizationFn != nil {
+		if org := i.opts.OrganizationFn(ctx); org != "" {
+			if err := awsxray.AddAnnotation(ctx, "organization", org); err != nil {
+				i.logger.Debug("X-Ray annotation failed", "error", err)
+			}
+		}
+	}
+
+	return ctx, seg
+}
+
+// streamingClientConn calls finish once with the outcome of a client
+// stream when its response is closed: the first error other than
+// io.EOF returned by Send, Receive or CloseResponse.
+type streamingClientConn struct {
+	connect.StreamingClientConn
+
+	finish func(error)
+	once   sync.Once
+
+	mu  sync.Mutex
+	err error
+}
+
+func (c *streamingClientConn) Send(msg any) error {
+	return c.record(c.StreamingClientConn.Send(msg))
+}
+
+func (c *streamingClientConn) Receive(msg any) error {
+	return c.record(c.StreamingClientConn.Receive(msg))
+}
+
+func (c *streamingClientConn) CloseResponse() error {
+	err := c.record(c.StreamingClientConn.CloseResponse())
+
+	c.once.Do(func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.finish(c.err)
 	})
+
+	return err
+}
+
+func (c *streamingClientConn) record(err error) error {
+	if err == nil || errors.Is(err, io.EOF) {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err == nil {
+		c.err = err
+	}
+
+	return err
 }
 
 // InstrumentHandler implements dindenault.TelemetryProvider.
@@ -68,6 +158,12 @@ func (p *Provider) InstrumentHandler(handler interface{}) interface{} {
 	return handler
 }
 
+// ReportPanic implements dindenault.PanicReporter by recording the
+// panic as a fault on the current X-Ray segment.
+func (p *Provider) ReportPanic(ctx context.Context, recovered any, _ []byte) {
+	_ = awsxray.AddError(ctx, fmt.Errorf("panic: %v", recovered))
+}
+
 // DefaultOrganizationFunction extracts the organization from Naviga ID auth claims.
 // Use this as OrganizationFn in dindenault.TelemetryOptions.
 func DefaultOrganizationFunction(ctx context.Context) string {